@@ -0,0 +1,144 @@
+package ss
+
+import (
+	"github.com/raz-varren/log"
+	"strings"
+)
+
+const (
+	//RolesAttr is the Socket attribute that holds a Socket's roles, in addition to the "roles" claim
+	RolesAttr = "roles"
+
+	//ScopesAttr is the Socket attribute that holds a Socket's scopes, in addition to the "scope" and "scp" claims
+	ScopesAttr = "scopes"
+)
+
+//ACL describes what a Socket needs before its events are dispatched to an event handler.
+//See SocketServer.Require.
+type ACL struct {
+	//Roles lists the roles that are allowed to emit the event, the Socket must have at least one of them.
+	//If Roles is empty, the Socket's roles are not checked.
+	Roles []string
+
+	//Scopes lists the scopes needed to emit the event, the Socket must have all of them
+	Scopes []string
+}
+
+//Require sets the ACL checked before events named eventName are dispatched to their handler.
+//Events denied by the ACL are not dispatched, and the client receives an ErrorEvent with the
+//CodeForbidden code instead.
+//
+//Require may be called before or after the event's handler is registered. Stream events registered
+//with SocketServer.OnStream are checked when the client opens the stream.
+//
+//eventName may also be a pattern registered with OnPattern, in which case the ACL applies to every event
+//dispatched to that pattern's event function that has no ACL of its own.
+func (serv *SocketServer) Require(eventName string, acl ACL) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.acls[eventName] = &acl
+}
+
+//allowed returns true if s satisfies the ACL of eventName, or the ACL of pattern if eventName has none.
+//pattern is the OnPattern pattern eventName was matched with, if any.
+func (serv *SocketServer) allowed(s *Socket, eventName, pattern string) bool {
+	serv.l.RLock()
+	acl, exists := serv.acls[eventName]
+	if !exists && pattern != "" {
+		acl, exists = serv.acls[pattern]
+	}
+	serv.l.RUnlock()
+
+	if !exists {
+		return true
+	}
+
+	if len(acl.Roles) > 0 {
+		hasRole := false
+		for _, role := range acl.Roles {
+			if s.HasRole(role) {
+				hasRole = true
+				break
+			}
+		}
+		if !hasRole {
+			return false
+		}
+	}
+
+	for _, scope := range acl.Scopes {
+		if !s.HasScope(scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//deny tells the client that it may not emit eventName
+func (serv *SocketServer) deny(s *Socket, eventName string) {
+	log.Debug.Println(s.ID(), "denied event:", eventName)
+	s.EmitError(eventName, CodeForbidden, "not allowed to emit "+eventName)
+}
+
+//Roles returns the roles in the "roles" claim of s, along with any roles stored in its RolesAttr attribute
+func (s *Socket) Roles() []string {
+	roles := s.claims.Strings("roles")
+	if v, exists := s.GetAttr(RolesAttr); exists {
+		roles = append(roles, stringList(v)...)
+	}
+	return roles
+}
+
+//Scopes returns the scopes in the "scope" and "scp" claims of s, along with any scopes stored
+//in its ScopesAttr attribute
+func (s *Socket) Scopes() []string {
+	scopes := append(s.claims.Strings("scope"), s.claims.Strings("scp")...)
+	if v, exists := s.GetAttr(ScopesAttr); exists {
+		scopes = append(scopes, stringList(v)...)
+	}
+	return scopes
+}
+
+//HasRole returns true if role is one of the roles of s
+func (s *Socket) HasRole(role string) bool {
+	return contains(s.Roles(), role)
+}
+
+//HasScope returns true if scope is one of the scopes of s
+func (s *Socket) HasScope(scope string) bool {
+	return contains(s.Scopes(), scope)
+}
+
+func contains(list []string, item string) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+//stringList converts a list of strings, a list of interface{} strings, or a
+//space separated string into a []string
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+
+	case []string:
+		return append([]string{}, v...)
+
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+
+	default:
+		return nil
+	}
+}
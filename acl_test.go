@@ -0,0 +1,96 @@
+package ss_test
+
+import (
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequire(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnConnect(func(s *ss.Socket) {
+		s.SetAttr(ss.RolesAttr, []string{s.Claims().String("role")})
+		s.SetAttr(ss.ScopesAttr, s.Claims().String("scope"))
+	})
+	serv.SetAuthenticator(ss.AuthenticatorFunc(func(r *http.Request) (ss.Claims, error) {
+		return ss.Claims{"role": r.Header.Get("X-Role"), "scope": r.Header.Get("X-Scope")}, nil
+	}))
+
+	serv.On("ban", func(s *ss.Socket, data []byte) {
+		s.Emit("banned", string(data))
+	})
+	serv.Require("ban", ss.ACL{Roles: []string{"admin", "moderator"}, Scopes: []string{"users:write"}})
+
+	srv := sstest.NewServer(t, serv)
+
+	tests := []struct {
+		role    string
+		scope   string
+		allowed bool
+	}{
+		{"moderator", "users:read users:write", true},
+		{"admin", "users:write", true},
+		{"admin", "users:read", false},
+		{"user", "users:write", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		c := srv.DialHeader(http.Header{"X-Role": []string{tt.role}, "X-Scope": []string{tt.scope}})
+
+		c.Emit("ban", "mallory")
+		if tt.allowed {
+			c.Expect("banned")
+			c.ExpectNone(ss.ErrorEvent, 20*time.Millisecond)
+		} else {
+			var e ss.EventError
+			err := c.Expect(ss.ErrorEvent).Unmarshal(&e)
+			if err != nil || e.Event != "ban" || e.Code != ss.CodeForbidden {
+				t.Errorf("%s %q: expected a forbidden error for ban, got %+v (%v)", tt.role, tt.scope, e, err)
+			}
+			c.ExpectNone("banned", 20*time.Millisecond)
+		}
+		c.Close()
+	}
+}
+
+func TestRequirePattern(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnConnect(func(s *ss.Socket) {
+		s.SetAttr(ss.RolesAttr, []string{s.Claims().String("role")})
+	})
+	serv.SetAuthenticator(ss.AuthenticatorFunc(func(r *http.Request) (ss.Claims, error) {
+		return ss.Claims{"role": r.Header.Get("X-Role")}, nil
+	}))
+
+	serv.OnPattern("room/{id}/msg", func(s *ss.Socket, msg *ss.Message) {
+		s.Emit("posted", msg.Param("id"))
+	})
+	serv.Require("room/{id}/msg", ss.ACL{Roles: []string{"member"}})
+	serv.Require("room/lobby/msg", ss.ACL{})
+
+	srv := sstest.NewServer(t, serv)
+	member := srv.DialHeader(http.Header{"X-Role": []string{"member"}})
+	guest := srv.DialHeader(http.Header{"X-Role": []string{"guest"}})
+
+	member.Emit("room/42/msg", "hi")
+	if id := member.Expect("posted").String(); id != "42" {
+		t.Errorf("expected a post to room 42, got %s", id)
+	}
+
+	guest.Emit("room/42/msg", "hi")
+	var e ss.EventError
+	err := guest.Expect(ss.ErrorEvent).Unmarshal(&e)
+	if err != nil || e.Event != "room/42/msg" || e.Code != ss.CodeForbidden {
+		t.Errorf("expected the pattern's ACL to forbid room/42/msg, got %+v (%v)", e, err)
+	}
+	guest.ExpectNone("posted", 20*time.Millisecond)
+
+	//the exact event name's ACL takes precedence over the pattern's
+	guest.Emit("room/lobby/msg", "hi")
+	if id := guest.Expect("posted").String(); id != "lobby" {
+		t.Errorf("expected a post to the lobby, got %s", id)
+	}
+}
@@ -0,0 +1,181 @@
+package ss
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+//connCounter counts the Sockets connected to a SocketServer, in total, per IP, and per identity
+type connCounter struct {
+	l          *sync.Mutex
+	total      int
+	ips        map[string]int
+	identities map[string]int
+}
+
+func newConnCounter() *connCounter {
+	return &connCounter{
+		l:          &sync.Mutex{},
+		ips:        make(map[string]int),
+		identities: make(map[string]int),
+	}
+}
+
+//connLimits are the limits checked by connCounter.acquire, 0 means no limit
+type connLimits struct {
+	total       int
+	perIP       int
+	perIdentity int
+}
+
+//acquire counts a new Socket from ip for identity, unless doing so would exceed one of the limits.
+//It returns 0 if the Socket was counted, otherwise the HTTP status code to reject the handshake with.
+//identity is not counted if it is empty.
+func (c *connCounter) acquire(ip, identity string, limits connLimits) int {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if limits.total > 0 && c.total >= limits.total {
+		return http.StatusServiceUnavailable
+	}
+	if limits.perIP > 0 && c.ips[ip] >= limits.perIP {
+		return http.StatusTooManyRequests
+	}
+	if limits.perIdentity > 0 && identity != "" && c.identities[identity] >= limits.perIdentity {
+		return http.StatusTooManyRequests
+	}
+
+	c.total++
+	c.ips[ip]++
+	if identity != "" {
+		c.identities[identity]++
+	}
+	return 0
+}
+
+//release stops counting a Socket counted by acquire
+func (c *connCounter) release(ip, identity string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.total--
+	if c.ips[ip]--; c.ips[ip] <= 0 {
+		delete(c.ips, ip)
+	}
+	if identity != "" {
+		if c.identities[identity]--; c.identities[identity] <= 0 {
+			delete(c.identities, identity)
+		}
+	}
+}
+
+//SetMaxSockets limits the number of Sockets connected to the SocketServer at once. Handshakes over
+//the limit are rejected with a 503 Service Unavailable response before the websocket is upgraded.
+//A max of 0, the default, means no limit.
+func (serv *SocketServer) SetMaxSockets(max int) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.limits.total = max
+}
+
+//SetMaxSocketsPerIP limits the number of Sockets connected to the SocketServer from a single IP address.
+//Handshakes over the limit are rejected with a 429 Too Many Requests response before the websocket is upgraded.
+//A max of 0, the default, means no limit. See SetTrustedProxies for servers running behind proxies.
+func (serv *SocketServer) SetMaxSocketsPerIP(max int) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.limits.perIP = max
+}
+
+//SetMaxSocketsPerIdentity limits the number of Sockets connected to the SocketServer for a single user, identified
+//by the "sub" claim returned by the SocketServer's Authenticator. Handshakes over the limit are rejected with a 429
+//Too Many Requests response before the websocket is upgraded. Sockets without a "sub" claim are not limited.
+//A max of 0, the default, means no limit.
+func (serv *SocketServer) SetMaxSocketsPerIdentity(max int) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.limits.perIdentity = max
+}
+
+//SetTrustedProxies sets the IP addresses or CIDR ranges, like "10.0.0.0/8", of the proxies in front of the SocketServer.
+//When a handshake comes from a trusted proxy, the client's IP is read from the X-Forwarded-For header, skipping
+//any trusted proxies at the end of the list, or from the X-Real-IP header. Headers sent by untrusted peers are ignored.
+//
+//An error is returned, and the trusted proxies are left unchanged, if one of proxies can not be parsed.
+func (serv *SocketServer) SetTrustedProxies(proxies ...string) error {
+	var nets []*net.IPNet
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, n)
+	}
+
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.trustedProxies = nets
+	return nil
+}
+
+//ClientIP returns the IP address of the client making the websocket handshake request r,
+//taking the SocketServer's trusted proxies into account
+func (serv *SocketServer) ClientIP(r *http.Request) string {
+	serv.l.RLock()
+	trusted := serv.trustedProxies
+	serv.l.RUnlock()
+
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	if !isTrusted(ip, trusted) {
+		return ip
+	}
+
+	//proxies may add their own header line instead of extending the client's, so every line counts
+	if xff := strings.Join(r.Header.Values("X-Forwarded-For"), ","); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break //a malformed entry, don't trust anything before it
+			}
+			ip = hop
+			if !isTrusted(hop, trusted) {
+				break
+			}
+		}
+		return ip
+	}
+
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(real) != nil {
+		return real
+	}
+	return ip
+}
+
+func isTrusted(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, n := range trusted {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
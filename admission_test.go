@@ -0,0 +1,116 @@
+package ss_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func dialStatus(t *testing.T, srv *sstest.Server, header http.Header) int {
+	t.Helper()
+
+	d := &websocket.Dialer{Subprotocols: []string{ss.SubProtocol}}
+	ws, resp, err := d.Dial(srv.URL, header)
+	if err == nil {
+		t.Cleanup(func() { ws.Close() })
+		return resp.StatusCode
+	}
+	if resp == nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestConnectionLimits(t *testing.T) {
+	serv := ss.NewServer()
+	serv.SetAuthenticator(ss.AuthenticatorFunc(func(r *http.Request) (ss.Claims, error) {
+		return ss.Claims{"sub": r.Header.Get("X-User")}, nil
+	}))
+	if err := serv.SetTrustedProxies("127.0.0.1", "::1"); err != nil {
+		t.Fatal(err)
+	}
+	serv.SetMaxSockets(4)
+	serv.SetMaxSocketsPerIP(2)
+	serv.SetMaxSocketsPerIdentity(1)
+
+	srv := sstest.NewServer(t, serv)
+	from := func(ip, user string) http.Header {
+		return http.Header{"X-Forwarded-For": []string{ip}, "X-User": []string{user}}
+	}
+
+	tests := []struct {
+		ip, user string
+		status   int
+	}{
+		{"10.0.0.1", "alice", http.StatusSwitchingProtocols},
+		{"10.0.0.1", "alice", http.StatusTooManyRequests}, //identity
+		{"10.0.0.1", "bob", http.StatusSwitchingProtocols},
+		{"10.0.0.1", "carol", http.StatusTooManyRequests}, //ip
+		{"10.0.0.2", "carol", http.StatusSwitchingProtocols},
+		{"10.0.0.3", "", http.StatusSwitchingProtocols},
+		{"10.0.0.4", "", http.StatusServiceUnavailable}, //total
+	}
+
+	for i, tt := range tests {
+		if status := dialStatus(t, srv, from(tt.ip, tt.user)); status != tt.status {
+			t.Errorf("%d %s %s: expected %d, got %d", i, tt.ip, tt.user, tt.status, status)
+		}
+	}
+
+	//closing sockets frees their slots
+	for _, s := range srv.GetSockets() {
+		s.Close()
+	}
+	deadline := time.Now().Add(sstest.DefaultTimeout)
+	for dialStatus(t, srv, from("10.0.0.1", "alice")) != http.StatusSwitchingProtocols {
+		if time.Now().After(deadline) {
+			t.Fatal("expected closed sockets to be released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	serv := ss.NewServer()
+	if err := serv.SetTrustedProxies("10.0.0.0/8", "not an ip"); err == nil {
+		t.Error("expected an error for an invalid proxy")
+	}
+	if err := serv.SetTrustedProxies("10.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remote string
+		xff    []string
+		realIP string
+		ip     string
+	}{
+		{"203.0.113.9:5000", nil, "", "203.0.113.9"},
+		{"203.0.113.9:5000", []string{"198.51.100.1"}, "", "203.0.113.9"},
+		{"10.1.2.3:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"10.1.2.3:5000", []string{"1.1.1.1, 198.51.100.1, 10.9.9.9"}, "", "198.51.100.1"},
+		{"10.1.2.3:5000", []string{"1.1.1.1", "198.51.100.1"}, "", "198.51.100.1"},
+		{"10.1.2.3:5000", []string{"1.1.1.1", "198.51.100.1, 10.9.9.9"}, "", "198.51.100.1"},
+		{"10.1.2.3:5000", nil, "198.51.100.2", "198.51.100.2"},
+		{"10.1.2.3:5000", []string{"garbage"}, "", "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/socket", nil)
+		r.RemoteAddr = tt.remote
+		for _, xff := range tt.xff {
+			r.Header.Add("X-Forwarded-For", xff)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+
+		if ip := serv.ClientIP(r); ip != tt.ip {
+			t.Errorf("%s %q %q: expected %s, got %s", tt.remote, tt.xff, tt.realIP, tt.ip, ip)
+		}
+	}
+}
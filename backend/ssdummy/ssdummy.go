@@ -73,3 +73,14 @@ func (d *DummyMHB) RoomcastFromBackend(rCast chan<- *ss.RoomMsg) {
 		log.Info.Println("roomcast message from backend, RoomName:", d.fromRoomName, "EventName:", d.fromRoomcastEventName, "Data:", d.fromData)
 	}
 }
+
+//ControlToBackend prints out a control message whenever a local websocket sends a control message to the backend
+func (d *DummyMHB) ControlToBackend(c *ss.ControlMsg) {
+	log.Info.Println("control message to backend, Action:", c.Action, "SocketID:", c.SocketID, "RoomName:", c.RoomName)
+}
+
+//ControlFromBackend prints a log message when the method is called. The dummy backend never sends control
+//messages to local websockets, since acting on made up socket IDs would not illustrate anything useful.
+func (d *DummyMHB) ControlFromBackend(cCast chan<- *ss.ControlMsg) {
+	log.Info.Println("ControlFromBackend method called")
+}
@@ -18,6 +18,7 @@ var (
 
 	ErrNilBroadcastChannel = errors.New("broadcast channel is not open yet")
 	ErrNilRoomcastChannel  = errors.New("roomcast channel is not open yet")
+	ErrNilControlChannel   = errors.New("control channel is not open yet")
 
 	ErrBadDataType = errors.New("bad data type used")
 	ErrBadContext  = errors.New("bad context used in transport")
@@ -27,6 +28,7 @@ type propagateServer struct {
 	sharedKey []byte
 	bChan     chan<- *ss.BroadcastMsg
 	rChan     chan<- *ss.RoomMsg
+	cChan     chan<- *ss.ControlMsg
 	insecure  bool
 	l         *sync.RWMutex
 }
@@ -145,3 +147,29 @@ func (p *propagateServer) DoRoomcast(ctx context.Context, r *transport.Roomcast)
 	tr.Success = true
 	return tr, nil
 }
+
+func (p *propagateServer) DoControl(ctx context.Context, c *transport.Control) (*transport.Result, error) {
+	tr := &transport.Result{Timestamp: c.Timestamp, Success: false}
+
+	err := p.checkCreds(ctx)
+	if err != nil {
+		log.Err.Println(err)
+		return tr, err
+	}
+
+	p.l.RLock()
+	cChan := p.cChan
+	p.l.RUnlock()
+	if cChan == nil {
+		return tr, ErrNilControlChannel
+	}
+
+	cChan <- &ss.ControlMsg{
+		Action:   ss.ControlAction(c.Action),
+		SocketID: c.SocketId,
+		RoomName: c.Room,
+	}
+
+	tr.Success = true
+	return tr, nil
+}
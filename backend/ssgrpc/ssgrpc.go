@@ -1,5 +1,5 @@
 /*
-Package ssgrpc provides a ss.MultihomeBackend interface that uses grpc with profobufs for synchronizing broadcasts and roomcasts between multiple Sacrificial Socket instances.
+Package ssgrpc provides a ss.MultihomeBackend interface that uses grpc with profobufs for synchronizing broadcasts, roomcasts, and socket control messages between multiple Sacrificial Socket instances.
 */
 package ssgrpc
 
@@ -195,6 +195,27 @@ func (g *GRPCMHB) RoomcastToBackend(r *ss.RoomMsg) {
 	}
 }
 
+//ControlToBackend propagates the control message to all active peer connections
+func (g *GRPCMHB) ControlToBackend(c *ss.ControlMsg) {
+	cCast := &transport.Control{
+		Timestamp: timestamp(),
+		Action:    string(c.Action),
+		SocketId:  c.SocketID,
+		Room:      c.RoomName,
+	}
+
+	g.l.RLock()
+	defer g.l.RUnlock()
+
+	for _, peer := range g.peers {
+		_, err := peer.client.DoControl(context.Background(), cCast)
+		if err != nil {
+			log.Err.Println(err)
+			continue
+		}
+	}
+}
+
 //BroadcastFromBackend listens on the local grpc service for calls from remote peers and
 //propagates broadcasts to locally connected websockets
 func (g *GRPCMHB) BroadcastFromBackend(b chan<- *ss.BroadcastMsg) {
@@ -211,6 +232,14 @@ func (g *GRPCMHB) RoomcastFromBackend(r chan<- *ss.RoomMsg) {
 	g.pServer.rChan = r
 }
 
+//ControlFromBackend listens on the local grpc service for calls from remote peers and
+//propagates control messages to locally connected websockets
+func (g *GRPCMHB) ControlFromBackend(c chan<- *ss.ControlMsg) {
+	g.pServer.l.Lock()
+	defer g.pServer.l.Unlock()
+	g.pServer.cChan = c
+}
+
 func getDataType(in interface{}) ([]byte, transport.DataType) {
 	switch i := in.(type) {
 	case string:
@@ -1,5 +1,7 @@
 /*
 Package token is used by package ssgrpc for generating and validating HMAC-SHA256 tokens sent as "per RPC credentials".
+
+Tokens are validated with package ssauth, which can also be used to authenticate end user websocket connections.
 */
 package token
 
@@ -7,11 +9,12 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/dvsekhvalnov/jose2go"
+	"github.com/raz-varren/sacrificial-socket/ssauth"
 	"time"
 )
 
 var (
-	ErrTokenExpired = errors.New("token expired")
+	ErrTokenExpired = ssauth.ErrTokenExpired
 	ErrBadPayload   = errors.New("payload is missing critical values")
 
 	ErrNoToken        = errors.New("user did not provide a token")
@@ -42,23 +45,19 @@ func GenUserToken(user string, expires time.Duration, signingKey []byte) (UserTo
 //error is nil if validation succeeded.
 func ValidateUserToken(token string, signingKey []byte) (UserToken, error) {
 	var u UserToken
-	payload, _, err := jose.Decode(token, signingKey)
+	claims, err := ssauth.Validate(token, signingKey, 0)
 	if err != nil {
 		return u, err
 	}
 
-	err = json.Unmarshal([]byte(payload), &u)
-	if err != nil {
-		return u, err
+	u.IAM = claims.String("iam")
+	if exp, ok := claims["exp"].(float64); ok {
+		u.EXP = int64(exp)
 	}
 
 	if u.IAM == "" || u.EXP == 0 {
 		return u, ErrBadPayload
 	}
 
-	if u.EXP < time.Now().Unix() {
-		return u, ErrTokenExpired
-	}
-
 	return u, nil
 }
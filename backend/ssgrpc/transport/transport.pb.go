@@ -12,6 +12,7 @@ It has these top-level messages:
 	Broadcast
 	Roomcast
 	Result
+	Control
 */
 package transport
 
@@ -176,10 +177,52 @@ func (m *Result) GetTimestamp() uint64 {
 	return 0
 }
 
+type Control struct {
+	// unix nano timestamp
+	Timestamp uint64 `protobuf:"fixed64,1,opt,name=timestamp" json:"timestamp,omitempty"`
+	Action    string `protobuf:"bytes,2,opt,name=action" json:"action,omitempty"`
+	SocketId  string `protobuf:"bytes,3,opt,name=socketId" json:"socketId,omitempty"`
+	Room      string `protobuf:"bytes,4,opt,name=room" json:"room,omitempty"`
+}
+
+func (m *Control) Reset()                    { *m = Control{} }
+func (m *Control) String() string            { return proto.CompactTextString(m) }
+func (*Control) ProtoMessage()               {}
+func (*Control) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Control) GetTimestamp() uint64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Control) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *Control) GetSocketId() string {
+	if m != nil {
+		return m.SocketId
+	}
+	return ""
+}
+
+func (m *Control) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Broadcast)(nil), "transport.Broadcast")
 	proto.RegisterType((*Roomcast)(nil), "transport.Roomcast")
 	proto.RegisterType((*Result)(nil), "transport.Result")
+	proto.RegisterType((*Control)(nil), "transport.Control")
 	proto.RegisterEnum("transport.DataType", DataType_name, DataType_value)
 }
 
@@ -196,6 +239,7 @@ const _ = grpc.SupportPackageIsVersion4
 type PropagateClient interface {
 	DoBroadcast(ctx context.Context, in *Broadcast, opts ...grpc.CallOption) (*Result, error)
 	DoRoomcast(ctx context.Context, in *Roomcast, opts ...grpc.CallOption) (*Result, error)
+	DoControl(ctx context.Context, in *Control, opts ...grpc.CallOption) (*Result, error)
 }
 
 type propagateClient struct {
@@ -224,11 +268,21 @@ func (c *propagateClient) DoRoomcast(ctx context.Context, in *Roomcast, opts ...
 	return out, nil
 }
 
+func (c *propagateClient) DoControl(ctx context.Context, in *Control, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := grpc.Invoke(ctx, "/transport.Propagate/DoControl", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Propagate service
 
 type PropagateServer interface {
 	DoBroadcast(context.Context, *Broadcast) (*Result, error)
 	DoRoomcast(context.Context, *Roomcast) (*Result, error)
+	DoControl(context.Context, *Control) (*Result, error)
 }
 
 func RegisterPropagateServer(s *grpc.Server, srv PropagateServer) {
@@ -271,6 +325,24 @@ func _Propagate_DoRoomcast_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Propagate_DoControl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Control)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PropagateServer).DoControl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/transport.Propagate/DoControl",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PropagateServer).DoControl(ctx, req.(*Control))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Propagate_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "transport.Propagate",
 	HandlerType: (*PropagateServer)(nil),
@@ -283,6 +355,10 @@ var _Propagate_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DoRoomcast",
 			Handler:    _Propagate_DoRoomcast_Handler,
 		},
+		{
+			MethodName: "DoControl",
+			Handler:    _Propagate_DoControl_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "transport.proto",
@@ -291,23 +367,27 @@ var _Propagate_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("transport.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 286 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0xd2, 0xcd, 0x4a, 0xc3, 0x40,
-	0x10, 0x07, 0x70, 0xb7, 0x49, 0xd3, 0x64, 0x14, 0x8d, 0x63, 0x0f, 0x41, 0x3c, 0x84, 0x1e, 0x24,
-	0x78, 0xa8, 0x50, 0xc5, 0xb3, 0x94, 0x5c, 0xf4, 0x50, 0x65, 0xdb, 0x17, 0x58, 0xd3, 0x45, 0x04,
-	0xd3, 0x09, 0xbb, 0x53, 0xa1, 0x77, 0xdf, 0xc1, 0xd7, 0x95, 0xc6, 0x7c, 0x29, 0x15, 0x7a, 0xca,
-	0xcc, 0x3f, 0xbb, 0xcb, 0x6f, 0x3f, 0xe0, 0x84, 0x8d, 0x5a, 0xd9, 0x82, 0x0c, 0x8f, 0x0b, 0x43,
-	0x4c, 0x18, 0x34, 0xc1, 0xe8, 0x53, 0x40, 0x30, 0x35, 0xa4, 0x96, 0x99, 0xb2, 0x8c, 0x17, 0x10,
-	0xf0, 0x5b, 0xae, 0x2d, 0xab, 0xbc, 0x88, 0x44, 0x2c, 0x12, 0x4f, 0xb6, 0x01, 0x0e, 0xa1, 0xaf,
-	0x3f, 0xf4, 0x8a, 0xa3, 0x5e, 0x2c, 0x92, 0x40, 0xfe, 0x34, 0x88, 0xe0, 0x2e, 0x15, 0xab, 0xc8,
-	0x89, 0x45, 0x72, 0x24, 0xcb, 0x1a, 0xaf, 0xc1, 0xdf, 0x7e, 0x17, 0x9b, 0x42, 0x47, 0x6e, 0x2c,
-	0x92, 0xe3, 0xc9, 0xd9, 0xb8, 0x45, 0xa4, 0xd5, 0x2f, 0xd9, 0x0c, 0x1a, 0x7d, 0x09, 0xf0, 0x25,
-	0x51, 0xbe, 0x87, 0x02, 0xc1, 0x35, 0x44, 0x79, 0x85, 0x28, 0xeb, 0x56, 0xe6, 0xec, 0x92, 0xb9,
-	0xff, 0xc8, 0xfa, 0xfb, 0xc8, 0xee, 0xc1, 0x93, 0xda, 0xae, 0xdf, 0x19, 0x23, 0x18, 0xd8, 0x75,
-	0x96, 0x69, 0x6b, 0x4b, 0x94, 0x2f, 0xeb, 0xf6, 0x37, 0xb8, 0xf7, 0x07, 0x7c, 0x75, 0x09, 0x7e,
-	0xbd, 0x2e, 0x0e, 0xc0, 0x99, 0x2f, 0x64, 0x78, 0xb0, 0x2d, 0xa6, 0x0f, 0xb3, 0x50, 0xa0, 0x0f,
-	0xee, 0xe3, 0xfc, 0x69, 0x16, 0xf6, 0x26, 0x1b, 0x08, 0x9e, 0x0d, 0x15, 0xea, 0x55, 0xb1, 0xc6,
-	0x3b, 0x38, 0x4c, 0xa9, 0xbd, 0x98, 0x61, 0x07, 0xd9, 0xa4, 0xe7, 0xa7, 0x9d, 0xb4, 0x42, 0xde,
-	0x02, 0xa4, 0xd4, 0x9c, 0x64, 0x77, 0x6f, 0x75, 0xb8, 0x63, 0xd6, 0x8b, 0x57, 0xbe, 0x8b, 0x9b,
-	0xef, 0x00, 0x00, 0x00, 0xff, 0xff, 0xdb, 0x8f, 0xc3, 0x46, 0x2a, 0x02, 0x00, 0x00,
+	// 338 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xcf, 0x4a, 0xeb, 0x40,
+	0x14, 0xc6, 0xef, 0xb4, 0x69, 0x9a, 0x9c, 0x7b, 0xb9, 0xd6, 0xb1, 0x48, 0x28, 0x2e, 0x42, 0x17,
+	0x12, 0x5c, 0x54, 0xa8, 0xe2, 0x5a, 0x6a, 0x36, 0x75, 0x51, 0x65, 0xda, 0x17, 0x18, 0xd3, 0x41,
+	0x8a, 0x4d, 0x4e, 0x98, 0x39, 0x15, 0xdc, 0xfb, 0x0e, 0x3e, 0x85, 0xef, 0x28, 0x8d, 0x93, 0x3f,
+	0x4a, 0xc5, 0xae, 0x72, 0xbe, 0x6f, 0x32, 0xf0, 0xfb, 0xbe, 0x39, 0x70, 0x40, 0x5a, 0x66, 0x26,
+	0x47, 0x4d, 0xa3, 0x5c, 0x23, 0x21, 0xf7, 0x2b, 0x63, 0xf8, 0xca, 0xc0, 0x9f, 0x68, 0x94, 0xcb,
+	0x44, 0x1a, 0xe2, 0x27, 0xe0, 0xd3, 0x2a, 0x55, 0x86, 0x64, 0x9a, 0x07, 0x2c, 0x64, 0x91, 0x2b,
+	0x6a, 0x83, 0xf7, 0xa1, 0xa3, 0x9e, 0x55, 0x46, 0x41, 0x2b, 0x64, 0x91, 0x2f, 0x3e, 0x05, 0xe7,
+	0xe0, 0x2c, 0x25, 0xc9, 0xa0, 0x1d, 0xb2, 0xe8, 0x9f, 0x28, 0x66, 0x7e, 0x0e, 0xde, 0xf6, 0xbb,
+	0x78, 0xc9, 0x55, 0xe0, 0x84, 0x2c, 0xfa, 0x3f, 0x3e, 0x1a, 0xd5, 0x10, 0xb1, 0x3d, 0x12, 0xd5,
+	0x4f, 0xc3, 0x37, 0x06, 0x9e, 0x40, 0x4c, 0xf7, 0xa0, 0xe0, 0xe0, 0x68, 0xc4, 0xd4, 0x42, 0x14,
+	0x73, 0x4d, 0xd6, 0xde, 0x45, 0xe6, 0xfc, 0x40, 0xd6, 0xd9, 0x87, 0xec, 0x1a, 0x5c, 0xa1, 0xcc,
+	0x66, 0x4d, 0x3c, 0x80, 0xae, 0xd9, 0x24, 0x89, 0x32, 0xa6, 0x80, 0xf2, 0x44, 0x29, 0xbf, 0x02,
+	0xb7, 0xbe, 0x01, 0x0f, 0x11, 0xba, 0x37, 0x98, 0x91, 0xc6, 0xf5, 0x2f, 0xc9, 0x8e, 0xc1, 0x95,
+	0x09, 0xad, 0x30, 0xb3, 0xd9, 0xac, 0xe2, 0x03, 0xf0, 0x0c, 0x26, 0x4f, 0x8a, 0xa6, 0x4b, 0x1b,
+	0xb0, 0xd2, 0x55, 0x1b, 0x4e, 0xdd, 0xc6, 0xd9, 0x29, 0x78, 0x65, 0x10, 0xde, 0x85, 0xf6, 0x7c,
+	0x21, 0x7a, 0x7f, 0xb6, 0xc3, 0x64, 0x3a, 0xeb, 0x31, 0xee, 0x81, 0x73, 0x3b, 0xbf, 0x9b, 0xf5,
+	0x5a, 0xe3, 0x77, 0x06, 0xfe, 0xbd, 0xc6, 0x5c, 0x3e, 0x4a, 0x52, 0xfc, 0x0a, 0xfe, 0xc6, 0x58,
+	0xaf, 0x42, 0xbf, 0x51, 0x4b, 0xe5, 0x0e, 0x0e, 0x1b, 0xae, 0xad, 0xe5, 0x12, 0x20, 0xc6, 0xea,
+	0xed, 0x9a, 0x6d, 0x96, 0xe6, 0xae, 0x5b, 0x63, 0xf0, 0x63, 0x2c, 0x6b, 0xe1, 0x8d, 0x73, 0xeb,
+	0xed, 0xb8, 0xf3, 0xe0, 0x16, 0xdb, 0x7b, 0xf1, 0x31, 0x00, 0x4b, 0xdd, 0x5d, 0x12, 0xd0, 0x02,
+	0x00, 0x00,
 }
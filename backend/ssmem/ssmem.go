@@ -0,0 +1,223 @@
+/*
+Package ssmem provides a ss.MultihomeBackend interface that synchronizes broadcasts, roomcasts, and socket control messages
+between multiple Sacrificial Socket instances running in the same process.
+
+Every backend created by the same Broker is connected to every other backend from that Broker:
+
+	broker := ssmem.NewBroker()
+
+	servA := ss.NewServer()
+	servA.SetMultihomeBackend(broker.NewBackend())
+
+	servB := ss.NewServer()
+	servB.SetMultihomeBackend(broker.NewBackend())
+
+ssmem is meant for testing cross server logic without running Redis, MongoDB, or GRPC. The Broker can inject latency and
+message loss to simulate an unreliable network between the servers.
+*/
+package ssmem
+
+import (
+	ss "github.com/raz-varren/sacrificial-socket"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	//queueSize is the number of messages of each kind that may be waiting to be consumed by a backend
+	queueSize = 256
+)
+
+//Broker delivers messages between the MemMHB backends that it creates
+type Broker struct {
+	l          *sync.RWMutex
+	backends   map[*MemMHB]bool
+	minLatency time.Duration
+	maxLatency time.Duration
+	lossRate   float64
+}
+
+//MemMHB implements the ss.MultihomeBackend interface and uses a Broker to
+//syncronize between multiple ss.SocketServer instances in the same process
+type MemMHB struct {
+	broker *Broker
+	bCast  chan *ss.BroadcastMsg
+	rCast  chan *ss.RoomMsg
+	cCast  chan *ss.ControlMsg
+	done   chan struct{}
+	once   *sync.Once
+}
+
+//NewBroker creates a new *Broker that delivers messages immediately and never loses them
+func NewBroker() *Broker {
+	return &Broker{
+		l:        &sync.RWMutex{},
+		backends: make(map[*MemMHB]bool),
+	}
+}
+
+//SetLatency delays the delivery of every message to each backend by a random duration between min and max.
+//Messages may arrive out of order when max is greater than min. Use 0, 0 to deliver messages immediately.
+func (b *Broker) SetLatency(min, max time.Duration) {
+	if max < min {
+		max = min
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+	b.minLatency = min
+	b.maxLatency = max
+}
+
+//SetLossRate sets the probability, between 0 and 1, that a message will not be delivered to a backend.
+//Each backend a message is sent to is decided independently.
+func (b *Broker) SetLossRate(rate float64) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	b.lossRate = rate
+}
+
+//NewBackend creates a new *MemMHB connected to every other backend created by b
+func (b *Broker) NewBackend() *MemMHB {
+	return &MemMHB{
+		broker: b,
+		bCast:  make(chan *ss.BroadcastMsg, queueSize),
+		rCast:  make(chan *ss.RoomMsg, queueSize),
+		cCast:  make(chan *ss.ControlMsg, queueSize),
+		done:   make(chan struct{}),
+		once:   &sync.Once{},
+	}
+}
+
+//publish calls deliver for every backend except from, unless the message is lost, after
+//waiting for the configured latency. Without latency each delivery completes before publish returns.
+func (b *Broker) publish(from *MemMHB, deliver func(to *MemMHB)) {
+	type delivery struct {
+		to    *MemMHB
+		delay time.Duration
+	}
+
+	b.l.RLock()
+	var deliveries []delivery
+	for to := range b.backends {
+		if to == from {
+			continue
+		}
+
+		if b.lossRate > 0 && rand.Float64() < b.lossRate {
+			continue
+		}
+
+		delay := b.minLatency
+		if b.maxLatency > b.minLatency {
+			delay += time.Duration(rand.Int63n(int64(b.maxLatency - b.minLatency)))
+		}
+		deliveries = append(deliveries, delivery{to, delay})
+	}
+	b.l.RUnlock()
+
+	for _, d := range deliveries {
+		if d.delay == 0 {
+			deliver(d.to)
+			continue
+		}
+
+		to := d.to
+		time.AfterFunc(d.delay, func() { deliver(to) })
+	}
+}
+
+//Init registers the backend with its Broker
+func (m *MemMHB) Init() {
+	m.broker.l.Lock()
+	defer m.broker.l.Unlock()
+	m.broker.backends[m] = true
+}
+
+//Shutdown unregisters the backend from its Broker. Messages still in flight to the backend are discarded.
+func (m *MemMHB) Shutdown() {
+	m.broker.l.Lock()
+	delete(m.broker.backends, m)
+	m.broker.l.Unlock()
+
+	m.once.Do(func() { close(m.done) })
+}
+
+//BroadcastToBackend sends b to every other backend
+func (m *MemMHB) BroadcastToBackend(b *ss.BroadcastMsg) {
+	b = &ss.BroadcastMsg{EventName: b.EventName, Data: copyData(b.Data)}
+	m.broker.publish(m, func(to *MemMHB) {
+		select {
+		case to.bCast <- b:
+		case <-to.done:
+		}
+	})
+}
+
+//RoomcastToBackend sends r to every other backend
+func (m *MemMHB) RoomcastToBackend(r *ss.RoomMsg) {
+	r = &ss.RoomMsg{RoomName: r.RoomName, EventName: r.EventName, Data: copyData(r.Data)}
+	m.broker.publish(m, func(to *MemMHB) {
+		select {
+		case to.rCast <- r:
+		case <-to.done:
+		}
+	})
+}
+
+//ControlToBackend sends c to every other backend
+func (m *MemMHB) ControlToBackend(c *ss.ControlMsg) {
+	c = &ss.ControlMsg{Action: c.Action, SocketID: c.SocketID, RoomName: c.RoomName}
+	m.broker.publish(m, func(to *MemMHB) {
+		select {
+		case to.cCast <- c:
+		case <-to.done:
+		}
+	})
+}
+
+//BroadcastFromBackend dispatches broadcasts sent by the other backends until Shutdown is called
+func (m *MemMHB) BroadcastFromBackend(bCast chan<- *ss.BroadcastMsg) {
+	for {
+		select {
+		case b := <-m.bCast:
+			bCast <- b
+		case <-m.done:
+			return
+		}
+	}
+}
+
+//RoomcastFromBackend dispatches roomcasts sent by the other backends until Shutdown is called
+func (m *MemMHB) RoomcastFromBackend(rCast chan<- *ss.RoomMsg) {
+	for {
+		select {
+		case r := <-m.rCast:
+			rCast <- r
+		case <-m.done:
+			return
+		}
+	}
+}
+
+//ControlFromBackend dispatches control messages sent by the other backends until Shutdown is called
+func (m *MemMHB) ControlFromBackend(cCast chan<- *ss.ControlMsg) {
+	for {
+		select {
+		case c := <-m.cCast:
+			cCast <- c
+		case <-m.done:
+			return
+		}
+	}
+}
+
+//copyData copies binary data so the sender can not modify it while it is in flight,
+//like it would have been copied by a network backend
+func copyData(data interface{}) interface{} {
+	if d, ok := data.([]byte); ok {
+		return append([]byte(nil), d...)
+	}
+	return data
+}
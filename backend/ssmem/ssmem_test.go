@@ -0,0 +1,63 @@
+package ssmem_test
+
+import (
+	ss "github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/backend/ssmem"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"testing"
+	"time"
+)
+
+func TestLatency(t *testing.T) {
+	cl := sstest.NewCluster(t, 2, nil)
+	cl.Broker.SetLatency(100*time.Millisecond, 100*time.Millisecond)
+	c := cl.Node(1).Dial()
+
+	start := time.Now()
+	cl.Node(0).Broadcast("news", "late")
+	c.Expect("news")
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("broadcast arrived after %s, expected at least 100ms", elapsed)
+	}
+}
+
+func TestLossRate(t *testing.T) {
+	cl := sstest.NewCluster(t, 2, nil)
+	local, remote := cl.Node(0).Dial(), cl.Node(1).Dial()
+
+	cl.Broker.SetLossRate(1)
+	cl.Node(0).Broadcast("news", "lost")
+	local.Expect("news")
+	remote.ExpectNone("news", 100*time.Millisecond)
+
+	cl.Broker.SetLossRate(0)
+	cl.Node(0).Broadcast("news", "found")
+	if msg := remote.Expect("news"); msg.String() != "found" {
+		t.Errorf("expected found, got %s", msg)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	broker := ssmem.NewBroker()
+	a, b := broker.NewBackend(), broker.NewBackend()
+
+	servA := ss.NewServer()
+	servA.SetMultihomeBackend(a)
+	servB := ss.NewServer()
+	servB.SetMultihomeBackend(b)
+
+	servB.Shutdown()
+
+	done := make(chan bool)
+	go func() {
+		a.BroadcastToBackend(&ss.BroadcastMsg{EventName: "news", Data: "nobody"})
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("BroadcastToBackend blocked on a backend that was shut down")
+	}
+}
@@ -37,6 +37,17 @@ type roomcast struct {
 	Read        bool          `bson:"Read"`
 }
 
+type control struct {
+	ID          bson.ObjectId `bson:"_id,omitempty"`
+	ServerName  string        `bson:"ServerName"`
+	ServerGroup string        `bson:"ServerGroup"`
+	Expire      time.Time     `bson:"Expire"`
+	Action      string        `bson:"Action"`
+	SocketID    string        `bson:"SocketID"`
+	RoomName    string        `bson:"RoomName"`
+	Read        bool          `bson:"Read"`
+}
+
 func (s *backendServer) setNextExpire() {
 	s.l.Lock()
 	defer s.l.Unlock()
@@ -58,3 +69,11 @@ func (r *roomcast) setNextExpire() {
 func (r *roomcast) expireNow() {
 	r.Expire = time.Now().Add(time.Minute * 5 * -1)
 }
+
+func (c *control) setNextExpire() {
+	c.Expire = time.Now().Add(time.Minute * 5)
+}
+
+func (c *control) expireNow() {
+	c.Expire = time.Now().Add(time.Minute * 5 * -1)
+}
@@ -1,5 +1,5 @@
 /*
-Package ssmongo provides a ss.MultihomeBackend interface that uses MongoDB for synchronizing broadcasts and roomcasts between multiple Sacrificial Socket instances.
+Package ssmongo provides a ss.MultihomeBackend interface that uses MongoDB for synchronizing broadcasts, roomcasts, and socket control messages between multiple Sacrificial Socket instances.
 */
 package ssmongo
 
@@ -21,14 +21,15 @@ type MMHB struct {
 	serverC       *mgo.Collection
 	roomcastC     *mgo.Collection
 	broadcastC    *mgo.Collection
+	controlC      *mgo.Collection
 	server        backendServer
 	pollFrequency time.Duration
 	l             *sync.RWMutex
 }
 
 //NewBackend returns a new instance of MMHB which satisfies the ss.MultihomeBackend interface.
-//A new database "SSMultihome" will be created at the specified mongoURL, and under it 3 collections "ss.activeServers",
-//"ss.roomcasts", and "ss.broadcasts" will be created if they don't already exist.
+//A new database "SSMultihome" will be created at the specified mongoURL, and under it 4 collections "ss.activeServers",
+//"ss.roomcasts", "ss.broadcasts", and "ss.controls" will be created if they don't already exist.
 //
 //serverName must be unique per running ss.SocketServer instance, otherwise broadcasts, and roomcasts
 //will not propogate correctly to the other running instances
@@ -55,6 +56,7 @@ func NewBackend(mongoURL, serverName, serverGroup string, pollFrequency time.Dur
 		serverC:       db.C("ss.activeServers"),
 		roomcastC:     db.C("ss.roomcasts"),
 		broadcastC:    db.C("ss.broadcasts"),
+		controlC:      db.C("ss.controls"),
 		server:        s,
 		pollFrequency: pollFrequency,
 		l:             &sync.RWMutex{},
@@ -76,10 +78,10 @@ func (mmhb *MMHB) getActiveServers() []backendServer {
 	return servers
 }
 
-//Init will create the "SSMultihome" database along with the "ss.activeServers", "ss.broadcasts", and "ss.roomcasts"
-//collections, as well as any neccessary indexes
+//Init will create the "SSMultihome" database along with the "ss.activeServers", "ss.broadcasts", "ss.roomcasts",
+//and "ss.controls" collections, as well as any neccessary indexes
 func (mmhb *MMHB) Init() {
-	cols := []*mgo.Collection{mmhb.serverC, mmhb.broadcastC, mmhb.roomcastC}
+	cols := []*mgo.Collection{mmhb.serverC, mmhb.broadcastC, mmhb.roomcastC, mmhb.controlC}
 	indexes := []mgo.Index{
 		mgo.Index{
 			Key:         []string{"Expire"},
@@ -176,6 +178,35 @@ func (mmhb *MMHB) RoomcastToBackend(r *ss.RoomMsg) {
 	}
 }
 
+//ControlToBackend will insert one control document into the ss.controls collection for each
+//server in the activeServers collection excluding itself, each time ControlToBackend is called.
+//
+//See documentation on the ss.MultihomeBackend interface for more information
+func (mmhb *MMHB) ControlToBackend(c *ss.ControlMsg) {
+	servers := mmhb.getActiveServers()
+
+	if len(servers) == 0 {
+		return
+	}
+	bulk := mmhb.controlC.Bulk()
+	for _, s := range servers {
+		ctl := control{
+			ServerName:  s.ServerName,
+			ServerGroup: s.ServerGroup,
+			Action:      string(c.Action),
+			SocketID:    c.SocketID,
+			RoomName:    c.RoomName,
+			Read:        false,
+		}
+		ctl.setNextExpire()
+		bulk.Insert(ctl)
+	}
+	_, err := bulk.Run()
+	if err != nil {
+		log.Err.Println(err)
+	}
+}
+
 //BroadcastFromBackend polls the ss.broadcasts collection, based on the pollFrequency provided to NewBackend, for new messages designated
 //to this serverName and inserts a ss.BroadcastMsg into b to be dispatched by the server
 //
@@ -300,6 +331,63 @@ func (mmhb *MMHB) RoomcastFromBackend(r chan<- *ss.RoomMsg) {
 	}
 }
 
+//ControlFromBackend polls the ss.controls collection, based on the pollFrequency provided to NewBackend, for new messages designated
+//to this serverName and inserts a ss.ControlMsg into c to be performed by the ss.SocketServer
+//
+//See documentation on the ss.MultihomeBackend interface for more information
+func (mmhb *MMHB) ControlFromBackend(c chan<- *ss.ControlMsg) {
+	server := mmhb.getServer()
+	for {
+		time.Sleep(mmhb.pollFrequency)
+		q := mmhb.controlC.Find(bson.M{
+			"ServerName":  server.ServerName,
+			"ServerGroup": server.ServerGroup,
+			"Read":        false,
+		}).Sort("Expire")
+
+		count, err := q.Count()
+
+		if err == io.EOF {
+			panic(err)
+		}
+		if err != nil {
+			log.Err.Println(err)
+			continue
+		}
+		if count == 0 {
+			continue
+		}
+
+		bulk := mmhb.controlC.Bulk()
+		iter := q.Iter()
+		var ctl control
+		i := 0
+		for iter.Next(&ctl) {
+			c <- &ss.ControlMsg{
+				Action:   ss.ControlAction(ctl.Action),
+				SocketID: ctl.SocketID,
+				RoomName: ctl.RoomName,
+			}
+			ctl.expireNow()
+			ctl.Read = true
+			bulk.Update(bson.M{"_id": ctl.ID}, bson.M{"$set": ctl})
+			i++
+			if i >= 900 {
+				_, err = bulk.Run()
+				if err != nil {
+					log.Err.Println(err)
+				}
+				bulk = mmhb.controlC.Bulk()
+				i = 0
+			}
+		}
+		_, err = bulk.Run()
+		if err != nil {
+			log.Err.Println(err)
+		}
+	}
+}
+
 //beat updates the Expire key for this server in the activeServers collection
 func (mmhb *MMHB) beat() {
 	server := mmhb.getServer()
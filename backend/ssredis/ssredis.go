@@ -1,5 +1,5 @@
 /*
-Package ssredis provides a ss.MultihomeBackend interface that uses Redis for synchronizing broadcasts and roomcasts between multiple Sacrificial Socket instances.
+Package ssredis provides a ss.MultihomeBackend interface that uses Redis for synchronizing broadcasts, roomcasts, and socket control messages between multiple Sacrificial Socket instances.
 */
 package ssredis
 
@@ -26,8 +26,10 @@ type RMHB struct {
 	o           *Options
 	rps         *redis.PubSub
 	bps         *redis.PubSub
+	cps         *redis.PubSub
 	roomPSName  string
 	bcastPSName string
+	ctlPSName   string
 }
 
 type Options struct {
@@ -71,13 +73,16 @@ func NewBackend(rOpts *redis.Options, ssrOpts *Options) (*RMHB, error) {
 
 	roomPSName := ssrOpts.ServerGroup + ":_ss_roomcasts"
 	bcastPSName := ssrOpts.ServerGroup + ":_ss_broadcasts"
+	ctlPSName := ssrOpts.ServerGroup + ":_ss_controls"
 
 	rmhb := &RMHB{
 		r:           rClient,
 		rps:         rClient.Subscribe(roomPSName),
 		bps:         rClient.Subscribe(bcastPSName),
+		cps:         rClient.Subscribe(ctlPSName),
 		roomPSName:  roomPSName,
 		bcastPSName: bcastPSName,
+		ctlPSName:   ctlPSName,
 		o:           ssrOpts,
 	}
 
@@ -93,6 +98,7 @@ func (r *RMHB) Init() {
 func (r *RMHB) Shutdown() {
 	r.rps.Close()
 	r.bps.Close()
+	r.cps.Close()
 	r.r.Close()
 }
 
@@ -137,6 +143,27 @@ func (r *RMHB) RoomcastToBackend(rm *ss.RoomMsg) {
 	}
 }
 
+//ControlToBackend will publish a control message to the redis backend
+func (r *RMHB) ControlToBackend(c *ss.ControlMsg) {
+	t := &controlTransmission{
+		ServerName: r.o.ServerName,
+		Action:     string(c.Action),
+		SocketID:   c.SocketID,
+		RoomName:   c.RoomName,
+	}
+
+	data, err := t.toJSON()
+	if err != nil {
+		log.Err.Println(err)
+		return
+	}
+
+	err = r.r.Publish(r.ctlPSName, string(data)).Err()
+	if err != nil {
+		log.Err.Println(err)
+	}
+}
+
 //BroadcastFromBackend will receive broadcast messages from redis and propogate them to the neccessary sockets
 func (r *RMHB) BroadcastFromBackend(bc chan<- *ss.BroadcastMsg) {
 	bChan := r.bps.Channel()
@@ -185,3 +212,28 @@ func (r *RMHB) RoomcastFromBackend(rc chan<- *ss.RoomMsg) {
 		}
 	}
 }
+
+//ControlFromBackend will receive control messages from redis and propogate them to the neccessary sockets
+func (r *RMHB) ControlFromBackend(cc chan<- *ss.ControlMsg) {
+	cChan := r.cps.Channel()
+
+	for d := range cChan {
+		var t controlTransmission
+
+		err := t.fromJSON([]byte(d.Payload))
+		if err != nil {
+			log.Err.Println(err)
+			continue
+		}
+
+		if t.ServerName == r.o.ServerName {
+			continue
+		}
+
+		cc <- &ss.ControlMsg{
+			Action:   ss.ControlAction(t.Action),
+			SocketID: t.SocketID,
+			RoomName: t.RoomName,
+		}
+	}
+}
@@ -17,6 +17,7 @@ const (
 var (
 	ErrBadDataType = errors.New("bad data type")
 	ErrNoEventName = errors.New("no event name")
+	ErrNoSocketID  = errors.New("no socket id")
 )
 
 type transmission struct {
@@ -70,6 +71,30 @@ func (t *transmission) fromJSON(data []byte) error {
 	return nil
 }
 
+type controlTransmission struct {
+	Action     string `json:"a"`
+	SocketID   string `json:"i"`
+	RoomName   string `json:"r,omitempty"`
+	ServerName string `json:"s"`
+}
+
+func (c *controlTransmission) toJSON() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *controlTransmission) fromJSON(data []byte) error {
+	err := json.Unmarshal(data, c)
+	if err != nil {
+		return err
+	}
+
+	if c.SocketID == "" {
+		return ErrNoSocketID
+	}
+
+	return nil
+}
+
 func getDataType(in interface{}) ([]byte, int) {
 	switch i := in.(type) {
 	case string:
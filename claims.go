@@ -0,0 +1,64 @@
+package ss
+
+import (
+	"net/http"
+)
+
+//Claims are the authenticated properties of a Socket's user, such as
+//the claims of a validated JWT
+type Claims map[string]interface{}
+
+//Authenticator authenticates websocket handshake requests. See package ssauth
+//for a JWT Authenticator.
+type Authenticator interface {
+	//Authenticate returns the Claims of the user making the request r. Returning an error
+	//rejects the handshake with a 401 Unauthorized response.
+	Authenticate(r *http.Request) (Claims, error)
+}
+
+//AuthenticatorFunc is an adapter that allows an ordinary function to be used as an Authenticator
+type AuthenticatorFunc func(r *http.Request) (Claims, error)
+
+//Authenticate calls f(r)
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (Claims, error) {
+	return f(r)
+}
+
+//Subject returns the "sub" claim, or an empty string if it is not set
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+//String returns the claim named key if it is a string, otherwise an empty string
+func (c Claims) String(key string) string {
+	s, _ := c[key].(string)
+	return s
+}
+
+//Strings returns the claim named key as a list of strings. The claim may either be a
+//list of strings, or a single space separated string like the OAuth 2.0 "scope" claim.
+func (c Claims) Strings(key string) []string {
+	return stringList(c[key])
+}
+
+//SetAuthenticator sets the Authenticator used to authenticate every websocket handshake. The Claims
+//it returns are available from the new Socket's Claims method.
+func (serv *SocketServer) SetAuthenticator(a Authenticator) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.authenticator = a
+}
+
+//Claims returns a copy of the Claims returned by the SocketServer's Authenticator when s connected.
+//If no Authenticator is set, Claims returns nil.
+func (s *Socket) Claims() Claims {
+	if s.claims == nil {
+		return nil
+	}
+
+	c := make(Claims, len(s.claims))
+	for k, v := range s.claims {
+		c[k] = v
+	}
+	return c
+}
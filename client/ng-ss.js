@@ -37,10 +37,26 @@
 					return socket.off(eventName);
 				};
 				
+				self.onError = function(callback){
+					socket.onError(scopeCB(callback));
+				};
+				
 				self.emit = function(eventName, data){
 					return socket.emit(eventName, data);
 				};
 				
+				self.onStream = function(eventName, onChunk, onEnd){
+					socket.onStream(eventName, scopeCB(onChunk), onEnd ? scopeCB(onEnd) : undefined);
+				};
+				
+				self.offStream = function(eventName){
+					return socket.offStream(eventName);
+				};
+				
+				self.emitStream = function(eventName, data, callback){
+					return socket.emitStream(eventName, data, callback ? scopeCB(callback) : undefined);
+				};
+				
 				self.close = function(){
 					return socket.close();
 				};
@@ -11,8 +11,15 @@ declare namespace SS {
                 intervalMS?: number;
         }
 
+	export interface eventError{
+                event: string;
+                code: string;
+                message: string;
+        }
+
 	export interface ssOpts{
                 reconnectOpts?: connOpts;
+                protocols?: string[] | (() => string[]);
         }
 }
 
@@ -27,9 +34,15 @@ declare class SS{
 
         on(eventName: string, callback: (data: any) => void): void;
         off(eventName: string): void;
+        onError(callback: (error: SS.eventError) => void): void;
 
         emit(eventName: string, data: any): void;
 
+        onStream(eventName: string, onChunk: (chunk: ArrayBuffer) => void, onEnd?: (error: string | null) => void): void;
+        offStream(eventName: string): void;
+
+        emitStream(eventName: string, data: ArrayBuffer, callback?: (error: string | null) => void): void;
+
         close(): any;
 }
 
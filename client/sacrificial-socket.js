@@ -18,9 +18,14 @@ if(typeof module === 'undefined'){
 	*         enabled: true, 
 	*         replayOnConnect: true, 
 	*         intervalMS: 5000
-	*     }
+	*     },
+	*     protocols: []
 	* }
 	*
+	* protocols is a list of extra websocket sub protocols to request along with "sac-sock", such as ['bearer.' + token]
+	* for servers using an ssauth.JWTAuthenticator. It may also be a function returning the list, which is called before
+	* every connection attempt so a fresh token can be used when reconnecting.
+	*
 	*/
 	var SS = function(url, opts){
 		opts = opts || {};
@@ -35,7 +40,25 @@ if(typeof module === 'undefined'){
 			dataStartCharCode   = 2,
 			dataStartChar       = String.fromCharCode(dataStartCharCode),
 			subProtocol         = 'sac-sock',
-			ws                  = new WebSocket(url, subProtocol);
+			streamPrefix        = '__ss_stream',
+			streamChunkPrefix   = '__ss_stream_chunk:',
+			streamChunkSize     = 32 * 1024,
+			streamWindow        = 16,
+			streamEvents        = {},
+			inStreams           = {},
+			outStreams          = {},
+			ws                  = new WebSocket(url, protocols());
+		
+		/**
+		* protocols is an internal function that returns the sub protocols to request when connecting
+		*
+		* @function protocols
+		*
+		*/
+		function protocols(){
+			var extra = (typeof opts.protocols === 'function') ? opts.protocols() : opts.protocols;
+			return [subProtocol].concat(extra || []);
+		}
 		
 		//blomp blomp-a noop noop a-noop noop noop
 		self.noop = function(){ };
@@ -100,10 +123,61 @@ if(typeof module === 'undefined'){
 			}
 			
 			if(eventName.length === 0) return; //no event to dispatch
+			if(eventName.indexOf(streamPrefix) === 0){
+				handleStreamMsg(eventName, (headers.J) ? JSON.parse(data) : data);
+				return;
+			}
 			if(typeof events[eventName] === 'undefined') return;
 			events[eventName].call(self, (headers.J) ? JSON.parse(data) : data);
 		};
 		
+		/**
+		* handleStreamMsg is an internal function for processing stream chunks and stream control messages
+		*
+		* @function handleStreamMsg
+		* @param {String} eventName - The name of the stream event
+		* @param {Object|ArrayBuffer} data - The stream control message, or the chunk's payload
+		*
+		*/
+		function handleStreamMsg(eventName, data){
+			var s;
+			if(eventName.indexOf(streamChunkPrefix) === 0){
+				s = inStreams[eventName.slice(streamChunkPrefix.length)];
+				if(!s) return;
+				s.handler.onChunk.call(self, data);
+				s.seq++;
+				self.emit('__ss_stream_ack', {id: s.id, seq: s.seq});
+				return;
+			}
+			
+			switch(eventName){
+			case '__ss_stream_open':
+				if(!streamEvents[data.event]){
+					self.emit('__ss_stream_abort', {id: data.id, error: 'no stream handler registered for event: '+data.event});
+					return;
+				}
+				inStreams[data.id] = {id: data.id, seq: 0, handler: streamEvents[data.event]};
+				break;
+			case '__ss_stream_end':
+				s = inStreams[data.id];
+				if(!s) return;
+				delete inStreams[data.id];
+				s.handler.onEnd.call(self, data.error || null);
+				break;
+			case '__ss_stream_ack':
+				s = outStreams[data.id];
+				if(!s) return;
+				s.acked = Math.max(s.acked, data.seq);
+				s.pump();
+				break;
+			case '__ss_stream_abort':
+				s = outStreams[data.id];
+				if(!s) return;
+				s.finish(data.error || 'stream aborted by server');
+				break;
+			}
+		}
+		
 		/**
 		* startReconnect is an internal function for reconnecting after an unexpected disconnect
 		*
@@ -113,7 +187,7 @@ if(typeof module === 'undefined'){
 		function startReconnect(){
 			setTimeout(function(){
 				console.log('attempting reconnect');
-				var newWS = new WebSocket(url, subProtocol);
+				var newWS = new WebSocket(url, protocols());
 				newWS.onmessage = ws.onmessage;
 				newWS.onclose = ws.onclose;
 				newWS.binaryType = ws.binaryType;
@@ -195,6 +269,17 @@ if(typeof module === 'undefined'){
 			}
 		};
 		
+		/**
+		* onError registers a callback to be run when the server rejects one of the client's events, or a server side
+		* handler reports an error with Socket.EmitError.
+		*
+		* @method onError
+		* @param {Function} callback(error) - The callback that will be ran with the error, an Object of the form {event: String, code: String, message: String}
+		*/
+		self.onError = function(callback){
+			events['__ss_error'] = callback;
+		};
+		
 		/**
 		* emit dispatches an event to the server
 		*
@@ -213,13 +298,17 @@ if(typeof module === 'undefined'){
 			}
 			var msg = '';
 			if(data instanceof ArrayBuffer){
-				var ab = new ArrayBuffer(data.byteLength+eventName.length+1),
+				var ab = new ArrayBuffer(data.byteLength+eventName.length+3),
 					newBuf = new DataView(ab),
 					oldBuf = new DataView(data),
 					i = 0;
 				for(var evtLen = eventName.length; i < evtLen; i++){
 					newBuf.setUint8(i, eventName.charCodeAt(i));
 				}
+				newBuf.setUint8(i, headerStartCharCode);
+				i++;
+				newBuf.setUint8(i, 'B'.charCodeAt(0));
+				i++;
 				newBuf.setUint8(i, dataStartCharCode);
 				i++;
 				for(var x = 0, xLen = oldBuf.byteLength; x < xLen; x++, i++){
@@ -227,13 +316,81 @@ if(typeof module === 'undefined'){
 				}
 				msg = ab;
 			}else if(typeof data === 'object'){
-				msg = eventName+dataStartChar+JSON.stringify(data);
+				msg = eventName+headerStartChar+'J'+dataStartChar+JSON.stringify(data);
 			}else{
-				msg = eventName+dataStartChar+data;
+				msg = eventName+headerStartChar+'S'+dataStartChar+data;
 			}
 			ws.send(msg);
 		};
 		
+		/**
+		* onStream registers callbacks to be run when the server streams data to the client for the given
+		* eventName using Socket.EmitStream.
+		*
+		* @method onStream
+		* @param {String} eventName - The name of the stream event being registered
+		* @param {Function} onChunk(chunk) - The callback that will be ran for every ArrayBuffer chunk received
+		* @param {Function} onEnd(error) - The callback that will be ran once the stream is finished. error is null if the stream completed successfully
+		*
+		*/
+		self.onStream = function(eventName, onChunk, onEnd){
+			streamEvents[eventName] = {onChunk: onChunk, onEnd: onEnd || self.noop};
+		};
+		
+		/**
+		* offStream unregisters a stream event
+		*
+		* @method offStream
+		* @param {String} eventName - The name of the stream event being unregistered
+		*/
+		self.offStream = function(eventName){
+			if(streamEvents[eventName]){
+				delete streamEvents[eventName];
+			}
+		};
+		
+		/**
+		* emitStream streams data to the server in chunks, to be read by a handler registered with SocketServer.OnStream.
+		* No more than 16 chunks will be in flight until the server acknowledges them.
+		*
+		* @method emitStream
+		* @param {String} eventName - The stream event to dispatch
+		* @param {ArrayBuffer} data - The data to be streamed to the server
+		* @param {Function} callback(error) - The callback that will be ran once all of the data has been sent. error is null if the stream was sent successfully
+		*/
+		self.emitStream = function(eventName, data, callback){
+			var id = Date.now().toString(36)+Math.random().toString(36).slice(2),
+				offset = 0,
+				sent = 0,
+				finished = false,
+				s = {acked: 0};
+			
+			callback = callback || self.noop;
+			
+			s.finish = function(error){
+				if(finished) return;
+				finished = true;
+				delete outStreams[id];
+				callback.call(self, error);
+			};
+			
+			s.pump = function(){
+				while(!finished && offset < data.byteLength && sent - s.acked < streamWindow){
+					self.emit(streamChunkPrefix+id, data.slice(offset, offset+streamChunkSize));
+					offset += streamChunkSize;
+					sent++;
+				}
+				if(!finished && offset >= data.byteLength){
+					self.emit('__ss_stream_end', {id: id});
+					s.finish(null);
+				}
+			};
+			
+			outStreams[id] = s;
+			self.emit('__ss_stream_open', {id: id, event: eventName});
+			s.pump();
+		};
+		
 		/**
 		* close will close the websocket connection, calling the "onDisconnect" event if one has been registered.
 		*
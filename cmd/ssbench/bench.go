@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gorilla/websocket"
+	ss "github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/ssproto"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type config struct {
+	targets  []string
+	mode     string
+	clients  int
+	rooms    int
+	senders  int
+	rate     float64
+	payload  int
+	duration time.Duration
+	drain    time.Duration
+}
+
+//result is the outcome of a benchmark run, all durations are in milliseconds
+type result struct {
+	Mode       string  `json:"mode"`
+	Nodes      int     `json:"nodes"`
+	Clients    int     `json:"clients"`
+	Rooms      int     `json:"rooms"`
+	Senders    int     `json:"senders"`
+	Rate       float64 `json:"rate"`
+	Payload    int     `json:"payload"`
+	Duration   float64 `json:"durationSeconds"`
+	Sent       int64   `json:"sent"`
+	Expected   int64   `json:"expected"`
+	Received   int64   `json:"received"`
+	Delivery   float64 `json:"deliveryRatio"`
+	SendRate   float64 `json:"sentPerSecond"`
+	RecvRate   float64 `json:"receivedPerSecond"`
+	LatencyMin float64 `json:"latencyMinMs"`
+	LatencyP50 float64 `json:"latencyP50Ms"`
+	LatencyP90 float64 `json:"latencyP90Ms"`
+	LatencyP99 float64 `json:"latencyP99Ms"`
+	LatencyMax float64 `json:"latencyMaxMs"`
+}
+
+//client is a single bench connection
+type client struct {
+	ws        *websocket.Conn
+	room      string
+	joined    chan struct{}
+	received  int64
+	latencies []time.Duration
+	l         *sync.Mutex
+	done      chan struct{}
+}
+
+func dialClient(url, room string) (*client, error) {
+	d := &websocket.Dialer{Subprotocols: []string{ss.SubProtocol}}
+	ws, _, err := d.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		ws:     ws,
+		room:   room,
+		joined: make(chan struct{}),
+		l:      &sync.Mutex{},
+		done:   make(chan struct{}),
+	}
+	go c.read()
+	return c, nil
+}
+
+func (c *client) read() {
+	defer close(c.done)
+
+	for {
+		_, frame, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		recvd := time.Now()
+
+		eventName, _, payload, err := ssproto.Decode(frame)
+		if err != nil {
+			continue
+		}
+
+		switch eventName {
+		case joinedEvent:
+			close(c.joined)
+
+		case msgEvent:
+			//bench payloads are "<sent unix nanos> <padding>"
+			sent := string(payload)
+			if i := strings.IndexByte(sent, ' '); i != -1 {
+				sent = sent[:i]
+			}
+			nanos, err := strconv.ParseInt(sent, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			atomic.AddInt64(&c.received, 1)
+			c.l.Lock()
+			c.latencies = append(c.latencies, recvd.Sub(time.Unix(0, nanos)))
+			c.l.Unlock()
+		}
+	}
+}
+
+func (c *client) emit(eventName, data string) error {
+	frame, err := ssproto.EncodeRaw(eventName, ssproto.TypeStr, []byte(data))
+	if err != nil {
+		return err
+	}
+	return c.ws.WriteMessage(websocket.TextMessage, frame)
+}
+
+func (c *client) close() {
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.ws.Close()
+	<-c.done
+}
+
+//run connects cfg.clients clients, joins them to their rooms, then emits events from
+//the first cfg.senders clients for cfg.duration
+func run(cfg *config) (*result, error) {
+	if cfg.rooms < 1 {
+		cfg.rooms = 1
+	}
+
+	var all []*client
+	defer func() {
+		for _, c := range all {
+			c.close()
+		}
+	}()
+
+	roomSize := make(map[string]int64)
+	for i := 0; i < cfg.clients; i++ {
+		room := "bench-room-" + strconv.Itoa(i%cfg.rooms)
+		c, err := dialClient(cfg.targets[i%len(cfg.targets)], room)
+		if err != nil {
+			return nil, fmt.Errorf("client %d: %v", i, err)
+		}
+		all = append(all, c)
+		roomSize[room]++
+
+		err = c.emit(joinEvent, room)
+		if err != nil {
+			return nil, fmt.Errorf("client %d: %v", i, err)
+		}
+	}
+
+	for i, c := range all {
+		select {
+		case <-c.joined:
+		case <-time.After(10 * time.Second):
+			return nil, fmt.Errorf("client %d: timed out joining %s", i, c.room)
+		case <-c.done:
+			return nil, fmt.Errorf("client %d: connection closed while joining %s", i, c.room)
+		}
+	}
+
+	//the timestamp and separator take up the first 20 bytes of the payload
+	padding := ""
+	if cfg.payload > 20 {
+		padding = strings.Repeat("x", cfg.payload-20)
+	}
+
+	var sent, expected int64
+	wg := &sync.WaitGroup{}
+	stop := time.Now().Add(cfg.duration)
+	start := time.Now()
+
+	for _, c := range all[:cfg.senders] {
+		wg.Add(1)
+		go func(c *client) {
+			defer wg.Done()
+
+			interval := time.Duration(float64(time.Second) / cfg.rate)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for now := range ticker.C {
+				if now.After(stop) {
+					return
+				}
+
+				data := strconv.FormatInt(time.Now().UnixNano(), 10) + " " + padding
+
+				var err error
+				if cfg.mode == "broadcast" {
+					err = c.emit(broadcastEvent, data)
+					atomic.AddInt64(&expected, int64(cfg.clients))
+				} else {
+					err = c.emit(roomcastEvent, c.room+" "+data)
+					atomic.AddInt64(&expected, roomSize[c.room])
+				}
+				if err != nil {
+					return
+				}
+				atomic.AddInt64(&sent, 1)
+			}
+		}(c)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	//wait for outstanding events, or until the drain period is over
+	drainUntil := time.Now().Add(cfg.drain)
+	for time.Now().Before(drainUntil) && totalReceived(all) < atomic.LoadInt64(&expected) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return summarize(cfg, all, elapsed, sent, expected), nil
+}
+
+func totalReceived(all []*client) int64 {
+	var n int64
+	for _, c := range all {
+		n += atomic.LoadInt64(&c.received)
+	}
+	return n
+}
+
+func summarize(cfg *config, all []*client, elapsed time.Duration, sent, expected int64) *result {
+	var latencies []time.Duration
+	for _, c := range all {
+		c.l.Lock()
+		latencies = append(latencies, c.latencies...)
+		c.l.Unlock()
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	received := int64(len(latencies))
+	secs := elapsed.Seconds()
+
+	res := &result{
+		Mode:     cfg.mode,
+		Nodes:    len(cfg.targets),
+		Clients:  cfg.clients,
+		Rooms:    cfg.rooms,
+		Senders:  cfg.senders,
+		Rate:     cfg.rate,
+		Payload:  cfg.payload,
+		Duration: secs,
+		Sent:     sent,
+		Expected: expected,
+		Received: received,
+		SendRate: float64(sent) / secs,
+		RecvRate: float64(received) / secs,
+	}
+
+	if expected > 0 {
+		res.Delivery = float64(received) / float64(expected)
+	}
+
+	if len(latencies) > 0 {
+		res.LatencyMin = ms(latencies[0])
+		res.LatencyP50 = ms(percentile(latencies, 0.50))
+		res.LatencyP90 = ms(percentile(latencies, 0.90))
+		res.LatencyP99 = ms(percentile(latencies, 0.99))
+		res.LatencyMax = ms(latencies[len(latencies)-1])
+	}
+
+	return res
+}
+
+//percentile returns the p'th percentile of sorted
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (r *result) print(w io.Writer) {
+	fmt.Fprintf(w, "mode:       %s\n", r.Mode)
+	fmt.Fprintf(w, "nodes:      %d\n", r.Nodes)
+	fmt.Fprintf(w, "clients:    %d in %d rooms, %d senders at %.1f/s, %d byte payloads\n", r.Clients, r.Rooms, r.Senders, r.Rate, r.Payload)
+	fmt.Fprintf(w, "duration:   %.2fs\n", r.Duration)
+	fmt.Fprintf(w, "sent:       %d (%.1f/s)\n", r.Sent, r.SendRate)
+	fmt.Fprintf(w, "received:   %d of %d expected (%.2f%%, %.1f/s)\n", r.Received, r.Expected, r.Delivery*100, r.RecvRate)
+	fmt.Fprintf(w, "latency:    min %.3fms  p50 %.3fms  p90 %.3fms  p99 %.3fms  max %.3fms\n",
+		r.LatencyMin, r.LatencyP50, r.LatencyP90, r.LatencyP99, r.LatencyMax)
+}
@@ -0,0 +1,124 @@
+/*
+ssbench is a load generator for measuring the roomcast and broadcast fan-out of Sacrificial-Socket servers.
+
+ssbench opens a number of concurrent sac-sock connections, spreads them across rooms, and has some of them emit
+events at a fixed rate. Every emitted event is roomcast or broadcast by the server back to the clients, and ssbench
+measures how long each copy takes to arrive, reporting latency percentiles, throughput, and the delivery ratio.
+
+By default ssbench starts its own servers in process, which makes it easy to compare numbers before and after
+a change to the hub or a MultihomeBackend:
+
+	ssbench -clients 500 -rooms 10 -senders 20 -rate 20 -duration 30s
+	ssbench -nodes 2 -backend redis -redis localhost:6379 -mode broadcast
+	ssbench -nodes 2 -backend grpc -grpcport 30101
+
+Clients are spread across the nodes round robin, so with two nodes half of every fan-out travels through the backend.
+
+To benchmark servers running elsewhere, start them with -serve, which runs a single bench server on the given
+address, then point ssbench at them with -urls:
+
+	ssbench -serve :8080 -backend redis -redis redishost:6379
+	ssbench -urls ws://host-a:8080/socket,ws://host-b:8080/socket
+
+Use -json to print the results as JSON for later comparison.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/raz-varren/log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	urls     = flag.String("urls", "", "comma separated sac-sock urls to benchmark. if empty, local bench servers are started")
+	nodes    = flag.Int("nodes", 1, "number of local bench servers to start when -urls is empty")
+	backend  = flag.String("backend", "mem", "MultihomeBackend used to connect local bench servers: mem, redis, or grpc")
+	redisURL = flag.String("redis", "localhost:6379", "redis host:port used by the redis backend")
+	grpcPort = flag.Int("grpcport", 30101, "first port used by the grpc backend, each node uses the next port")
+	serve    = flag.String("serve", "", "only run a bench server on this host:port, for use with -urls from another machine")
+
+	mode     = flag.String("mode", "roomcast", "fan-out to measure: roomcast or broadcast")
+	clients  = flag.Int("clients", 100, "number of concurrent connections")
+	rooms    = flag.Int("rooms", 10, "number of rooms the clients are spread across")
+	senders  = flag.Int("senders", 10, "number of clients that emit events")
+	rate     = flag.Float64("rate", 10, "events emitted per second by each sender")
+	payload  = flag.Int("payload", 64, "size in bytes of each event's payload")
+	duration = flag.Duration("duration", 10*time.Second, "how long to emit events for")
+	drain    = flag.Duration("drain", 2*time.Second, "how long to wait for outstanding events after the last emit")
+	asJSON   = flag.Bool("json", false, "print the results as JSON")
+)
+
+func main() {
+	flag.Parse()
+
+	log.SetDefaultLogger(log.NewLogger(os.Stderr, log.LogLevelWrn))
+
+	if *serve != "" {
+		serv, err := newBenchServer(0)
+		check(err)
+		http.Handle("/socket", serv)
+		log.Err.Println(http.ListenAndServe(*serve, nil))
+		return
+	}
+
+	if *mode != "roomcast" && *mode != "broadcast" {
+		check(fmt.Errorf("unknown mode %q", *mode))
+	}
+	if *senders > *clients {
+		*senders = *clients
+	}
+
+	targets := splitList(*urls)
+	if len(targets) == 0 {
+		cluster, err := startCluster(*nodes)
+		check(err)
+		defer cluster.shutdown()
+		targets = cluster.urls
+	}
+
+	cfg := &config{
+		targets:  targets,
+		mode:     *mode,
+		clients:  *clients,
+		rooms:    *rooms,
+		senders:  *senders,
+		rate:     *rate,
+		payload:  *payload,
+		duration: *duration,
+		drain:    *drain,
+	}
+
+	res, err := run(cfg)
+	check(err)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		check(enc.Encode(res))
+		return
+	}
+	res.print(os.Stdout)
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ssbench:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-redis/redis"
+	ss "github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/backend/ssgrpc"
+	"github.com/raz-varren/sacrificial-socket/backend/ssmem"
+	"github.com/raz-varren/sacrificial-socket/backend/ssredis"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	joinEvent      = "bench:join"
+	joinedEvent    = "bench:joined"
+	roomcastEvent  = "bench:roomcast"
+	broadcastEvent = "bench:broadcast"
+	msgEvent       = "bench:msg"
+
+	//redisGroup keeps bench traffic away from any real servers sharing the redis instance
+	redisGroup = "ssbench"
+)
+
+//cluster is a group of local bench servers
+type cluster struct {
+	servers   []*ss.SocketServer
+	listeners []net.Listener
+	urls      []string
+}
+
+//newBenchServer returns a SocketServer with the bench event handlers registered, using the
+//MultihomeBackend selected by the -backend flag. node is the server's index in a local cluster.
+func newBenchServer(node int) (*ss.SocketServer, error) {
+	serv := ss.NewServer()
+
+	serv.On(joinEvent, func(s *ss.Socket, data []byte) {
+		s.Join(string(data))
+		s.Emit(joinedEvent, string(data))
+	})
+
+	//roomcast payloads are "<room> <bench payload>"
+	serv.On(roomcastEvent, func(s *ss.Socket, data []byte) {
+		msg := string(data)
+		i := strings.IndexByte(msg, ' ')
+		if i == -1 {
+			return
+		}
+		s.Roomcast(msg[:i], msgEvent, msg[i+1:])
+	})
+
+	serv.On(broadcastEvent, func(s *ss.Socket, data []byte) {
+		s.Broadcast(msgEvent, string(data))
+	})
+
+	b, err := newBackend(node)
+	if err != nil {
+		return nil, err
+	}
+	if b != nil {
+		serv.SetMultihomeBackend(b)
+	}
+
+	return serv, nil
+}
+
+var memBroker = ssmem.NewBroker()
+
+func newBackend(node int) (ss.MultihomeBackend, error) {
+	switch *backend {
+	case "mem":
+		if *serve != "" {
+			return nil, nil //nothing to share a memory backend with
+		}
+		return memBroker.NewBackend(), nil
+
+	case "redis":
+		return ssredis.NewBackend(&redis.Options{Addr: *redisURL}, &ssredis.Options{ServerGroup: redisGroup})
+
+	case "grpc":
+		if *serve != "" {
+			return nil, fmt.Errorf("the grpc backend can only be used with local bench servers")
+		}
+
+		var peers []string
+		for i := 0; i < *nodes; i++ {
+			if i != node {
+				peers = append(peers, "127.0.0.1:"+strconv.Itoa(*grpcPort+i))
+			}
+		}
+		return ssgrpc.NewInsecureBackend("127.0.0.1:"+strconv.Itoa(*grpcPort+node), peers), nil
+
+	default:
+		return nil, fmt.Errorf("unknown backend %q", *backend)
+	}
+}
+
+//startCluster starts n bench servers on random local ports
+func startCluster(n int) (*cluster, error) {
+	c := &cluster{}
+
+	for i := 0; i < n; i++ {
+		serv, err := newBenchServer(i)
+		if err != nil {
+			c.shutdown()
+			return nil, err
+		}
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			serv.Shutdown()
+			c.shutdown()
+			return nil, err
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/socket", serv)
+		go http.Serve(l, mux)
+
+		c.servers = append(c.servers, serv)
+		c.listeners = append(c.listeners, l)
+		c.urls = append(c.urls, "ws://"+l.Addr().String()+"/socket")
+	}
+
+	if *backend == "grpc" && n > 1 {
+		time.Sleep(time.Second) //give the grpc peers a chance to connect to each other
+	}
+
+	return c, nil
+}
+
+func (c *cluster) shutdown() {
+	for _, l := range c.listeners {
+		l.Close()
+	}
+	for _, serv := range c.servers {
+		serv.Shutdown()
+	}
+}
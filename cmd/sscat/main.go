@@ -0,0 +1,398 @@
+/*
+sscat is a command line client for sac-sock websocket endpoints.
+
+sscat connects to a Sacrificial-Socket server, prints every event it receives along with its data type, and sends
+events read from stdin, or from a script file, one command per line:
+
+	sscat -url ws://localhost:8080/socket
+	sscat -url ws://localhost:8080/socket -script ./commands.txt
+
+The following commands are supported:
+
+	emit [-s|-j|-b] <event> [data]   send an event to the server
+	wait <event> [timeout]           wait for an event to be received, sscat exits with status 1 if it times out
+	sleep <duration>                 pause before running the next command
+	close                            close the connection and exit
+
+emit sends data as a string, unless it is a JSON object or array, in which case it is sent as JSON. Use -s or -j to
+choose the data type explicitly, or -b to send base64 encoded data as binary. Durations use the time.ParseDuration
+format, such as 500ms or 2s. Blank lines and lines starting with # are ignored.
+
+Received events are printed as:
+
+	< <event> <S|B|J> <data>
+
+Binary data is printed base64 encoded, and strings containing control characters are quoted.
+*/
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gorilla/websocket"
+	ss "github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/ssproto"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	//maxQueued is the number of received events kept around for wait commands
+	maxQueued = 1024
+
+	defaultWait = 5 * time.Second
+)
+
+var (
+	url     = flag.String("url", "ws://localhost:8080/socket", "sac-sock websocket url to connect to")
+	script  = flag.String("script", "", "file to read commands from instead of stdin")
+	origin  = flag.String("origin", "", "Origin header to send with the websocket handshake")
+	linger  = flag.Duration("linger", time.Second, "how long to keep printing received events after the last command")
+	headers headerFlags
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrUsage          = errors.New("wrong number of arguments")
+	ErrWaitTimeout    = errors.New("timed out waiting for event")
+	ErrClosed         = errors.New("connection closed")
+)
+
+//headerFlags collects repeated -H "Name: value" flags
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(v string) error {
+	if !strings.Contains(v, ":") {
+		return errors.New("header must be in the form \"Name: value\"")
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+type event struct {
+	name     string
+	dataType ssproto.DataType
+	payload  []byte
+}
+
+type conn struct {
+	ws     *websocket.Conn
+	l      *sync.Mutex
+	queue  []*event
+	notify chan struct{}
+	done   chan struct{}
+}
+
+func main() {
+	flag.Var(&headers, "H", "header to send with the websocket handshake, in the form \"Name: value\". may be repeated")
+	flag.Parse()
+
+	in := io.Reader(os.Stdin)
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	c, err := dial()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connect:", err)
+		os.Exit(1)
+	}
+
+	err = c.run(in)
+	c.close()
+	if err != nil && err != ErrClosed {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func dial() (*conn, error) {
+	h := http.Header{}
+	for _, hdr := range headers {
+		kv := strings.SplitN(hdr, ":", 2)
+		h.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	if *origin != "" {
+		h.Set("Origin", *origin)
+	}
+
+	d := &websocket.Dialer{Subprotocols: []string{ss.SubProtocol}}
+	ws, resp, err := d.Dial(*url, h)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%v (%s)", err, resp.Status)
+		}
+		return nil, err
+	}
+
+	fmt.Fprintln(os.Stderr, "connected to", *url)
+
+	c := &conn{
+		ws:     ws,
+		l:      &sync.Mutex{},
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go c.read()
+	return c, nil
+}
+
+//read prints every received event and queues it for wait commands
+func (c *conn) read() {
+	defer close(c.done)
+
+	for {
+		msgType, frame, err := c.ws.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				fmt.Fprintln(os.Stderr, "! connection closed:", err)
+			}
+			return
+		}
+
+		name, dataType, payload, err := ssproto.Decode(frame)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "! bad frame: %v: %q\n", err, frame)
+			continue
+		}
+		if dataType == ssproto.TypeUnknown {
+			dataType = ssproto.TypeStr
+			if msgType == websocket.BinaryMessage {
+				dataType = ssproto.TypeBin
+			}
+		}
+
+		fmt.Printf("< %s %s %s\n", name, dataType, format(dataType, payload))
+
+		c.l.Lock()
+		if len(c.queue) == maxQueued {
+			c.queue = c.queue[1:]
+		}
+		c.queue = append(c.queue, &event{name, dataType, payload})
+		c.l.Unlock()
+
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+//run executes the commands read from in until in is exhausted, a close command is
+//run, a command fails, or the server closes the connection
+func (c *conn) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if line == "close" {
+			return nil
+		}
+
+		err := c.command(line)
+		if err == ErrClosed {
+			return err
+		}
+		if err != nil {
+			err = fmt.Errorf("line %d: %s: %v", lineNum, line, err)
+			if *script != "" {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "!", err) //keep going in interactive mode
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case <-time.After(*linger):
+		return nil
+	case <-c.done:
+		return ErrClosed
+	}
+}
+
+func (c *conn) command(line string) error {
+	cmd, args := next(line)
+
+	switch cmd {
+	case "emit":
+		return c.emit(args)
+
+	case "wait":
+		name, rest := next(args)
+		if name == "" {
+			return ErrUsage
+		}
+
+		timeout := defaultWait
+		if rest != "" {
+			var err error
+			timeout, err = time.ParseDuration(rest)
+			if err != nil {
+				return err
+			}
+		}
+		return c.wait(name, timeout)
+
+	case "sleep":
+		d, err := time.ParseDuration(args)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(d):
+			return nil
+		case <-c.done:
+			return ErrClosed
+		}
+
+	default:
+		return ErrUnknownCommand
+	}
+}
+
+//emit parses the arguments of an emit command and sends the event
+func (c *conn) emit(args string) error {
+	dataType := ssproto.TypeUnknown
+
+	opt, rest := next(args)
+	switch opt {
+	case "-s":
+		dataType, args = ssproto.TypeStr, rest
+	case "-j":
+		dataType, args = ssproto.TypeJSON, rest
+	case "-b":
+		dataType, args = ssproto.TypeBin, rest
+	}
+
+	name, data := next(args)
+	if name == "" {
+		return ErrUsage
+	}
+
+	if dataType == ssproto.TypeUnknown {
+		dataType = ssproto.TypeStr
+		isObj := strings.HasPrefix(data, "{") || strings.HasPrefix(data, "[")
+		if isObj && json.Valid([]byte(data)) {
+			dataType = ssproto.TypeJSON
+		}
+	}
+
+	payload := []byte(data)
+	if dataType == ssproto.TypeBin {
+		var err error
+		payload, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	frame, err := ssproto.EncodeRaw(name, dataType, payload)
+	if err != nil {
+		return err
+	}
+
+	msgType := websocket.TextMessage
+	if dataType == ssproto.TypeBin {
+		msgType = websocket.BinaryMessage
+	}
+
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	fmt.Printf("> %s %s %s\n", name, dataType, format(dataType, payload))
+	return c.ws.WriteMessage(msgType, frame)
+}
+
+//wait removes the first queued event named name, waiting up to timeout for one to arrive
+func (c *conn) wait(name string, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		c.l.Lock()
+		for i, e := range c.queue {
+			if e.name == name {
+				c.queue = append(c.queue[:i], c.queue[i+1:]...)
+				c.l.Unlock()
+				return nil
+			}
+		}
+		c.l.Unlock()
+
+		select {
+		case <-c.notify:
+		case <-c.done:
+			return ErrClosed
+		case <-deadline.C:
+			return ErrWaitTimeout
+		}
+	}
+}
+
+//close sends a normal closure close frame and waits briefly for the server to close the connection
+func (c *conn) close() {
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+	}
+	c.ws.Close()
+}
+
+//next splits off the first space separated word of s
+func next(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i == -1 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+//format returns a printable representation of payload
+func format(dataType ssproto.DataType, payload []byte) string {
+	if dataType == ssproto.TypeBin {
+		return base64.StdEncoding.EncodeToString(payload)
+	}
+
+	for _, r := range string(payload) {
+		if unicode.IsControl(r) {
+			return strconv.Quote(string(payload))
+		}
+	}
+	return string(payload)
+}
@@ -0,0 +1,69 @@
+package ss
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const (
+	socketKey contextKey = iota
+	messageKey
+)
+
+//SocketFromContext returns the Socket carried by ctx. Contexts returned by Socket.Context and passed
+//to functions registered with OnContext carry their Socket, whose attributes and claims can be read
+//with Socket.GetAttr and Socket.Claims.
+func SocketFromContext(ctx context.Context) (*Socket, bool) {
+	s, ok := ctx.Value(socketKey).(*Socket)
+	return s, ok
+}
+
+//MessageFromContext returns the Message carried by the context passed to a function registered with OnContext
+func MessageFromContext(ctx context.Context) (*Message, bool) {
+	msg, ok := ctx.Value(messageKey).(*Message)
+	return msg, ok
+}
+
+//Context returns a context that is cancelled when s is closed, which includes the SocketServer shutting
+//down. It carries s, see SocketFromContext, along with any values added by the SocketServer's ConnContext
+//function. Pass it to database calls and other work done on behalf of s so it stops once the client is gone.
+func (s *Socket) Context() context.Context {
+	return s.ctx
+}
+
+//OnContext has the same functionality as OnMessage, but the registered event function also receives
+//a context derived from Socket.Context, which is cancelled when the Socket is closed. The context
+//carries the Message as well, see MessageFromContext.
+//
+//Any event functions registered with OnContext, must be safe for concurrent use by multiple
+//go routines
+func (serv *SocketServer) OnContext(eventName string, handleFunc func(context.Context, *Socket, *Message)) {
+	serv.OnMessage(eventName, func(s *Socket, msg *Message) {
+		handleFunc(context.WithValue(s.Context(), messageKey, msg), s, msg)
+	})
+}
+
+//SetConnContext sets a function that can modify the context of every new Socket, for example to add trace
+//data found in the websocket handshake request r. ctx already carries the new Socket, and the returned context
+//must be derived from ctx. The function is called before the OnConnect functions.
+func (serv *SocketServer) SetConnContext(connContext func(ctx context.Context, r *http.Request) context.Context) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.connContext = connContext
+}
+
+//socketContext returns the context of a new Socket
+func (serv *SocketServer) socketContext(s *Socket, r *http.Request) (context.Context, context.CancelFunc) {
+	ctx := context.WithValue(serv.ctx, socketKey, s)
+
+	serv.l.RLock()
+	connContext := serv.connContext
+	serv.l.RUnlock()
+
+	if connContext != nil && r != nil {
+		ctx = connContext(ctx, r)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,58 @@
+package ss_test
+
+import (
+	"context"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type traceKey struct{}
+
+func TestOnContext(t *testing.T) {
+	done := make(chan error, 1)
+
+	serv := ss.NewServer()
+	serv.SetConnContext(func(ctx context.Context, r *http.Request) context.Context {
+		return context.WithValue(ctx, traceKey{}, r.Header.Get("X-Trace-Id"))
+	})
+	serv.OnContext("slow", func(ctx context.Context, s *ss.Socket, msg *ss.Message) {
+		cs, _ := ss.SocketFromContext(ctx)
+		cmsg, _ := ss.MessageFromContext(ctx)
+		if cs != s || cmsg != msg || ctx.Value(traceKey{}) != "abc123" {
+			t.Errorf("expected the context to carry the socket, message, and trace id")
+		}
+
+		s.Emit("started", "")
+		<-ctx.Done()
+		done <- ctx.Err()
+	})
+
+	srv := sstest.NewServer(t, serv)
+
+	c := srv.DialHeader(http.Header{"X-Trace-Id": []string{"abc123"}})
+	c.Emit("slow", "")
+	c.Expect("started")
+	c.Disconnect()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("expected the context to be cancelled, got %v", err)
+		}
+	case <-time.After(sstest.DefaultTimeout):
+		t.Fatal("expected the context to be cancelled when the socket closed")
+	}
+
+	c = srv.Dial()
+	s, _ := srv.GetSocket(c.ID())
+	srv.Close()
+
+	select {
+	case <-s.Context().Done():
+	case <-time.After(sstest.DefaultTimeout):
+		t.Fatal("expected the context to be cancelled when the server shut down")
+	}
+}
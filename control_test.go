@@ -0,0 +1,64 @@
+package ss_test
+
+import (
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"testing"
+	"time"
+)
+
+//eventually fails the test with msg if cond does not become true within sstest.DefaultTimeout
+func eventually(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(sstest.DefaultTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSocketControlAcrossServers(t *testing.T) {
+	cl := sstest.NewCluster(t, 2, nil)
+	local, remote := cl.Node(0).Dial(), cl.Node(1).Dial()
+	ls, _ := cl.Node(0).GetSocket(local.ID())
+	rs, _ := cl.Node(1).GetSocket(remote.ID())
+
+	//the socket is connected to the other server, so the join is sent through the MultihomeBackend
+	cl.Node(0).SocketJoin(remote.ID(), "lobby")
+	eventually(t, "remote socket did not join lobby", func() bool { return rs.InRoom("lobby") })
+	if ls.InRoom("lobby") {
+		t.Error("expected only the remote socket to join lobby")
+	}
+
+	cl.Node(0).Roomcast("lobby", "news", "lobby only")
+	remote.Expect("news")
+	local.ExpectNone("news", 50*time.Millisecond)
+
+	cl.Node(0).SocketLeave(remote.ID(), "lobby")
+	eventually(t, "remote socket did not leave lobby", func() bool { return !rs.InRoom("lobby") })
+
+	cl.Node(0).Kick(remote.ID())
+	remote.ExpectClosed()
+	if local.Closed() {
+		t.Error("expected only the remote socket to be kicked")
+	}
+}
+
+func TestSocketControlLocal(t *testing.T) {
+	cl := sstest.NewCluster(t, 2, nil)
+	local, remote := cl.Node(0).Dial(), cl.Node(1).Dial()
+	ls, _ := cl.Node(0).GetSocket(local.ID())
+
+	cl.Node(0).SocketJoin(local.ID(), "lobby")
+	eventually(t, "local socket did not join lobby", func() bool { return ls.InRoom("lobby") })
+
+	cl.Node(0).SocketLeave(local.ID(), "lobby")
+	eventually(t, "local socket did not leave lobby", func() bool { return !ls.InRoom("lobby") })
+
+	cl.Node(0).Kick(local.ID())
+	local.ExpectClosed()
+	if remote.Closed() {
+		t.Error("expected only the local socket to be kicked")
+	}
+}
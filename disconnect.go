@@ -0,0 +1,176 @@
+package ss
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/log"
+	"net"
+	"strconv"
+	"time"
+)
+
+//DisconnectCause describes why a Socket was disconnected
+type DisconnectCause string
+
+const (
+	//CauseClientClose means the client sent a close frame, DisconnectReason.Code and Text hold its close code and text
+	CauseClientClose DisconnectCause = "client close"
+
+	//CauseReadError means reading from the connection failed, usually because the network connection dropped
+	CauseReadError DisconnectCause = "read error"
+
+	//CausePingTimeout means the client did not answer a keepalive ping in time, see SocketServer.SetPingInterval
+	CausePingTimeout DisconnectCause = "ping timeout"
+
+	//CauseWriteError means writing to the connection failed
+	CauseWriteError DisconnectCause = "write error"
+
+	//CauseServerClose means the Socket was closed with Socket.Close or Socket.CloseWithReason
+	CauseServerClose DisconnectCause = "server close"
+
+	//CauseKicked means the Socket was disconnected with SocketServer.Kick
+	CauseKicked DisconnectCause = "kicked"
+
+	//CauseShutdown means the SocketServer was shut down
+	CauseShutdown DisconnectCause = "server shutdown"
+
+	//CausePanic means one of the Socket's event functions panicked, see SocketServer.SetClosePanicking
+	CausePanic DisconnectCause = "handler panic"
+)
+
+const (
+	//closeWriteWait is how long writing a close frame may take before the connection is closed anyway
+	closeWriteWait = time.Second
+)
+
+//DisconnectReason describes why a Socket was disconnected. See SocketServer.OnDisconnectReason.
+type DisconnectReason struct {
+	//Cause is the kind of event that disconnected the Socket
+	Cause DisconnectCause
+
+	//Code is the websocket close code received from the client or sent by the server. It is
+	//websocket.CloseAbnormalClosure if the connection dropped without a close frame.
+	Code int
+
+	//Text is the close text received from the client or sent by the server
+	Text string
+
+	//Err is the read or write error that disconnected the Socket, if any
+	Err error
+}
+
+func (r *DisconnectReason) String() string {
+	str := string(r.Cause)
+	if r.Code != 0 {
+		str += " (" + strconv.Itoa(r.Code)
+		if r.Text != "" {
+			str += " " + r.Text
+		}
+		str += ")"
+	}
+	if r.Err != nil {
+		str += ": " + r.Err.Error()
+	}
+	return str
+}
+
+//OnDisconnectReason registers an event function to be called as soon as a Socket connection is closed,
+//along with the reason it was closed. It is called right after the functions registered with OnDisconnect.
+//OnDisconnectReason may be called more than once, the event functions are called in the order they were registered.
+func (serv *SocketServer) OnDisconnectReason(handleFunc func(*Socket, *DisconnectReason)) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.onDisconnectReasonFuncs = append(serv.onDisconnectReasonFuncs, handleFunc)
+}
+
+//SetPingInterval makes the SocketServer send a websocket ping to every Socket each interval. Sockets that
+//do not send anything, including the pong answering a ping, within interval plus timeout are disconnected
+//with the CausePingTimeout reason. An interval of 0, the default, disables pings.
+//
+//SetPingInterval only affects Sockets that connect after it is called.
+func (serv *SocketServer) SetPingInterval(interval, timeout time.Duration) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.pingInterval = interval
+	serv.pingTimeout = timeout
+}
+
+//DisconnectReason returns the reason s was disconnected, or nil if s is still connected
+func (s *Socket) DisconnectReason() *DisconnectReason {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	return s.reason
+}
+
+//CloseWithReason sends the client a websocket close frame with code and text, then closes s.
+//The close frame is sent on a best effort basis, s is closed even if an error is returned.
+//
+//text must not be longer than 123 bytes. The codes 4000 through 4999 are available for
+//application specific reasons.
+func (s *Socket) CloseWithReason(code int, text string) error {
+	return s.disconnect(CauseServerClose, code, text)
+}
+
+//disconnect sends a close frame with code and text, then closes s with the reason cause
+func (s *Socket) disconnect(cause DisconnectCause, code int, text string) error {
+	err := s.writeClose(code, text)
+	if err != nil {
+		log.Debug.Println(s.ID(), "could not send close frame:", err)
+	}
+	s.close(&DisconnectReason{Cause: cause, Code: code, Text: text})
+	return err
+}
+
+func (s *Socket) writeClose(code int, text string) error {
+	s.l.RLock()
+	closed := s.closed
+	s.l.RUnlock()
+
+	if closed {
+		return ErrSocketClosed
+	}
+	return s.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(closeWriteWait))
+}
+
+//keepalive pings s every interval until s is closed
+func (s *Socket) keepalive(interval, timeout time.Duration) {
+	s.ws.SetReadDeadline(time.Now().Add(interval + timeout))
+	s.ws.SetPongHandler(func(string) error {
+		return s.ws.SetReadDeadline(time.Now().Add(interval + timeout))
+	})
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-t.C:
+				err := s.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout))
+				if err != nil {
+					s.close(&DisconnectReason{Cause: CauseWriteError, Code: websocket.CloseAbnormalClosure, Err: err})
+					return
+				}
+			}
+		}
+	}()
+}
+
+//readReason turns the error returned while reading from s into a DisconnectReason
+func readReason(err error) *DisconnectReason {
+	//gorilla reports a connection that dropped without a close frame as a CloseError with the abnormal closure code
+	if ce, ok := err.(*websocket.CloseError); ok && ce.Code != websocket.CloseAbnormalClosure {
+		return &DisconnectReason{Cause: CauseClientClose, Code: ce.Code, Text: ce.Text}
+	}
+
+	if err == websocket.ErrReadLimit { //gorilla has already sent the close frame
+		return &DisconnectReason{Cause: CauseMessageTooBig, Code: websocket.CloseMessageTooBig, Err: err}
+	}
+
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return &DisconnectReason{Cause: CausePingTimeout, Code: websocket.CloseAbnormalClosure, Err: err}
+	}
+
+	return &DisconnectReason{Cause: CauseReadError, Code: websocket.CloseAbnormalClosure, Err: err}
+}
@@ -0,0 +1,93 @@
+package ss_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"testing"
+	"time"
+)
+
+func newReasonServer(t *testing.T) (*sstest.Server, chan *ss.DisconnectReason) {
+	reasons := make(chan *ss.DisconnectReason, 1)
+
+	serv := ss.NewServer()
+	serv.On("logout", func(s *ss.Socket, data []byte) {
+		s.CloseWithReason(4001, "logged out")
+	})
+	serv.OnDisconnectReason(func(s *ss.Socket, r *ss.DisconnectReason) {
+		if s.DisconnectReason() != r {
+			t.Errorf("expected Socket.DisconnectReason to return %v, got %v", r, s.DisconnectReason())
+		}
+		reasons <- r
+	})
+
+	return sstest.NewServer(t, serv), reasons
+}
+
+func expectReason(t *testing.T, reasons chan *ss.DisconnectReason, cause ss.DisconnectCause, code int) {
+	t.Helper()
+
+	select {
+	case r := <-reasons:
+		if r.Cause != cause || r.Code != code {
+			t.Errorf("expected %s (%d), got %v", cause, code, r)
+		}
+	case <-time.After(sstest.DefaultTimeout):
+		t.Fatalf("expected %s (%d), OnDisconnectReason was not called", cause, code)
+	}
+}
+
+func TestDisconnectReason(t *testing.T) {
+	srv, reasons := newReasonServer(t)
+
+	c := srv.Dial()
+	c.CloseWithReason(4002, "bye")
+	expectReason(t, reasons, ss.CauseClientClose, 4002)
+
+	c = srv.Dial()
+	c.Disconnect()
+	expectReason(t, reasons, ss.CauseReadError, websocket.CloseAbnormalClosure)
+
+	c = srv.Dial()
+	c.Emit("logout", "")
+	c.ExpectClosed()
+	expectReason(t, reasons, ss.CauseServerClose, 4001)
+	if err, ok := c.Err().(*websocket.CloseError); !ok || err.Code != 4001 || err.Text != "logged out" {
+		t.Errorf("expected the client to receive 4001 logged out, got %v", c.Err())
+	}
+
+	c = srv.Dial()
+	srv.Kick(c.ID())
+	c.ExpectClosed()
+	expectReason(t, reasons, ss.CauseKicked, websocket.ClosePolicyViolation)
+
+	c = srv.Dial()
+	srv.Close()
+	c.ExpectClosed()
+	expectReason(t, reasons, ss.CauseShutdown, websocket.CloseGoingAway)
+}
+
+func TestPingTimeout(t *testing.T) {
+	srv, reasons := newReasonServer(t)
+	srv.SetPingInterval(20*time.Millisecond, 20*time.Millisecond)
+
+	//answers pings, since the client is always reading
+	c := srv.Dial()
+	time.Sleep(100 * time.Millisecond)
+	if c.Closed() {
+		t.Fatal("expected a client answering pings to stay connected")
+	}
+	c.Close()
+	expectReason(t, reasons, ss.CauseClientClose, websocket.CloseNormalClosure)
+
+	//never reads, so never answers pings
+	d := &websocket.Dialer{Subprotocols: []string{ss.SubProtocol}}
+	ws, _, err := d.Dial(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	expectReason(t, reasons, ss.CausePingTimeout, websocket.CloseAbnormalClosure)
+}
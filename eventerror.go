@@ -0,0 +1,28 @@
+package ss
+
+const (
+	//ErrorEvent is the event emitted to a client when one of its events is rejected by
+	//the SocketServer, or when a handler calls Socket.EmitError. Its payload is an EventError.
+	ErrorEvent = "__ss_error"
+
+	//CodeForbidden is the EventError code sent when a Socket does not have the roles or
+	//scopes required by an event's ACL
+	CodeForbidden = "forbidden"
+)
+
+//EventError is the JSON payload of an ErrorEvent
+type EventError struct {
+	//Event is the name of the event that caused the error
+	Event string `json:"event"`
+
+	//Code is a short machine readable description of the error, such as CodeForbidden
+	Code string `json:"code"`
+
+	//Message is a human readable description of the error
+	Message string `json:"message"`
+}
+
+//EmitError emits an ErrorEvent to the client, reporting that the event eventName failed
+func (s *Socket) EmitError(eventName, code, message string) error {
+	return s.Emit(ErrorEvent, &EventError{Event: eventName, Code: code, Message: message})
+}
@@ -120,7 +120,11 @@ func EchoJSON(s *ss.Socket, data []byte) {
 
 func Join(s *ss.Socket, data []byte) {
 	d := string(data)
-	s.Join(d)
+	err := s.Join(d)
+	if err != nil {
+		s.Emit("echo", "could not join room "+d+": "+err.Error())
+		return
+	}
 	s.Emit("echo", "joined room:"+d)
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/raz-varren/log"
 	ss "github.com/raz-varren/sacrificial-socket"
 	"net/http"
+	"strings"
 )
 
 func main() {
@@ -26,9 +27,17 @@ func join(s *ss.Socket, data []byte) {
 	//just one room at a time for the simple example
 	currentRooms := s.GetRooms()
 	for _, room := range currentRooms {
+		if strings.HasPrefix(room, ss.ReservedRoomPrefix) {
+			continue
+		}
 		s.Leave(room)
 	}
-	s.Join(string(data))
+
+	err := s.Join(string(data))
+	if err != nil {
+		s.Emit("error", err.Error())
+		return
+	}
 	s.Emit("joinedRoom", string(data))
 }
 
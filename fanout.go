@@ -0,0 +1,218 @@
+package ss
+
+import (
+	"errors"
+	"github.com/raz-varren/log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrFanoutQuota = errors.New("fan-out quota exceeded")
+)
+
+//FanoutQuota limits how much fan-out Roomcasts and Broadcasts may generate per second. The fan-out of a cast is
+//the number of Sockets connected to this SocketServer that it is sent to, plus one for the MultihomeBackend if one
+//is set, since the SocketServer can not know how many Sockets other SocketServers will send it to.
+//
+//Bursts of up to one second's worth of fan-out are allowed, and a single cast larger than that is allowed once
+//the quota has fully recovered. Zero values mean no limit.
+type FanoutQuota struct {
+	//Recipients is the number of recipients per second
+	Recipients float64
+
+	//Bytes is the number of bytes per second, counted as the size of the encoded event times its recipients
+	Bytes float64
+
+	//MaxWait is how long a cast over the quota is queued, waiting for the quota to recover, before it is
+	//rejected with ErrFanoutQuota. A MaxWait of 0 rejects casts over the quota right away.
+	MaxWait time.Duration
+}
+
+func (q *FanoutQuota) enabled() bool {
+	return q.Recipients > 0 || q.Bytes > 0
+}
+
+//SetFanoutQuota sets the FanoutQuota shared by all the Roomcasts, Broadcasts, and Socketcasts sent by the SocketServer
+//and its Sockets. Casts received from the MultihomeBackend are not counted.
+func (serv *SocketServer) SetFanoutQuota(q FanoutQuota) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.fanoutQuota = q
+}
+
+//SetSocketFanoutQuota sets the FanoutQuota of the Roomcasts, Broadcasts, and Socketcasts sent by each Socket, it applies
+//on top of the quota set with SetFanoutQuota. Every Socket has its own quota.
+func (serv *SocketServer) SetSocketFanoutQuota(q FanoutQuota) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.socketFanoutQuota = q
+}
+
+//fanoutBucket is a token bucket for recipients and bytes, holding up to one second's worth of each
+type fanoutBucket struct {
+	l          *sync.Mutex
+	recipients float64
+	bytes      float64
+	last       time.Time
+}
+
+func newFanoutBucket() *fanoutBucket {
+	return &fanoutBucket{l: &sync.Mutex{}}
+}
+
+//take takes recipients and bytes from b if it has enough of both, otherwise it returns how long until it will.
+//Casts bigger than the bucket only need the bucket to be full.
+func (b *fanoutBucket) take(q *FanoutQuota, recipients, bytes float64) time.Duration {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	now := time.Now()
+	if b.last.IsZero() {
+		b.recipients, b.bytes = q.Recipients, q.Bytes
+	} else {
+		elapsed := now.Sub(b.last).Seconds()
+		b.recipients = refill(b.recipients, q.Recipients, elapsed)
+		b.bytes = refill(b.bytes, q.Bytes, elapsed)
+	}
+	b.last = now
+
+	wait := tokenWait(b.recipients, recipients, q.Recipients)
+	if w := tokenWait(b.bytes, bytes, q.Bytes); w > wait {
+		wait = w
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	b.recipients -= recipients
+	b.bytes -= bytes
+	return 0
+}
+
+//refund gives back recipients and bytes taken from b for a cast that was not sent
+func (b *fanoutBucket) refund(q *FanoutQuota, recipients, bytes float64) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	b.recipients = refill(b.recipients+recipients, q.Recipients, 0)
+	b.bytes = refill(b.bytes+bytes, q.Bytes, 0)
+}
+
+func refill(tokens, rate, elapsed float64) float64 {
+	tokens += elapsed * rate
+	if tokens > rate {
+		tokens = rate
+	}
+	return tokens
+}
+
+//tokenWait returns how long until there are enough tokens for cost, which is 0 if rate is 0
+func tokenWait(tokens, cost, rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	if cost > rate {
+		cost = rate
+	}
+	if tokens >= cost {
+		return 0
+	}
+	return time.Duration((cost-tokens)/rate*float64(time.Second)) + time.Millisecond
+}
+
+//reserve waits until each of the buckets has quota for the cast, or returns ErrFanoutQuota if
+//that would take longer than the quota's MaxWait
+func reserve(b *fanoutBucket, q *FanoutQuota, recipients, bytes float64, done <-chan struct{}) error {
+	deadline := time.Now().Add(q.MaxWait)
+	for {
+		wait := b.take(q, recipients, bytes)
+		if wait == 0 {
+			return nil
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return ErrFanoutQuota
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-done:
+			return ErrFanoutQuota
+		}
+	}
+}
+
+//checkFanout applies the SocketServer's FanoutQuota, and the Socket's if s is not nil, to a cast of
+//eventName and data to roomName, or to every Socket if all is true
+func (serv *SocketServer) checkFanout(s *Socket, roomName string, all bool, eventName string, data interface{}) error {
+	serv.l.RLock()
+	q, sq := serv.fanoutQuota, serv.socketFanoutQuota
+	serv.l.RUnlock()
+
+	if s == nil || !sq.enabled() {
+		sq = FanoutQuota{}
+	}
+	if !q.enabled() && !sq.enabled() {
+		return nil
+	}
+
+	recipients := float64(serv.hub.countRecipients(roomName, all))
+	if recipients == 0 {
+		return nil
+	}
+
+	var bytes float64
+	if q.Bytes > 0 || sq.Bytes > 0 {
+		d, _, err := emitData(eventName, data)
+		if err != nil {
+			return err
+		}
+		bytes = recipients * float64(len(d))
+	}
+
+	var done <-chan struct{}
+	if s != nil {
+		done = s.done
+	}
+
+	if sq.enabled() {
+		err := reserve(s.fanout, &sq, recipients, bytes, done)
+		if err != nil {
+			log.Warn.Println(s.ID(), "exceeded its fan-out quota with", eventName)
+			return err
+		}
+	}
+
+	if q.enabled() {
+		err := reserve(serv.fanout, &q, recipients, bytes, done)
+		if err != nil {
+			log.Warn.Println("fan-out quota exceeded by", eventName)
+			if sq.enabled() { //the cast is not sent, so the Socket is not charged for it
+				s.fanout.refund(&sq, recipients, bytes)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+//cast sends a Roomcast to roomName, or a Broadcast if all is true, on behalf of s, which is nil for
+//casts sent by the SocketServer itself
+func (serv *SocketServer) cast(s *Socket, roomName string, all bool, eventName string, data interface{}) error {
+	err := serv.validateOutgoingData(eventName, data)
+	if err != nil {
+		log.Warn.Println("dropping invalid", eventName, "payload:", err)
+		return err
+	}
+
+	err = serv.checkFanout(s, roomName, all, eventName, data)
+	if err != nil {
+		return err
+	}
+
+	if all {
+		serv.hub.broadcast(&BroadcastMsg{eventName, data})
+	} else {
+		serv.hub.roomcast(&RoomMsg{roomName, eventName, data})
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package ss_test
+
+import (
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"testing"
+	"time"
+)
+
+func TestSocketFanoutQuota(t *testing.T) {
+	serv := ss.NewServer()
+	serv.SetSocketFanoutQuota(ss.FanoutQuota{Recipients: 4})
+	serv.On("join", func(s *ss.Socket, data []byte) {
+		s.Join("lobby")
+		s.Emit("joined", "")
+	})
+	serv.On("shout", func(s *ss.Socket, data []byte) {
+		if err := s.Roomcast("lobby", "news", string(data)); err != nil {
+			s.Emit("rejected", err.Error())
+		}
+	})
+
+	srv := sstest.NewServer(t, serv)
+	a, b := srv.Dial(), srv.Dial()
+	a.Emit("join", "")
+	a.Expect("joined")
+	b.Emit("join", "")
+	b.Expect("joined")
+
+	//each shout reaches 2 sockets, so the quota of 4 allows 2 shouts
+	for i := 0; i < 3; i++ {
+		a.Emit("shout", "")
+		a.Receive("news", 50*time.Millisecond)
+	}
+	if msg := a.Expect("rejected").String(); msg != ss.ErrFanoutQuota.Error() {
+		t.Errorf("expected %v, got %s", ss.ErrFanoutQuota, msg)
+	}
+
+	//the quota is per socket
+	b.Emit("shout", "")
+	b.Expect("news")
+	b.ExpectNone("rejected", 20*time.Millisecond)
+
+	//the server's own casts are not limited by socket quotas
+	for i := 0; i < 5; i++ {
+		if err := srv.Roomcast("lobby", "news", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFanoutQuotaQueue(t *testing.T) {
+	serv := ss.NewServer()
+	srv := sstest.NewServer(t, serv)
+	c := srv.Dial()
+
+	serv.SetFanoutQuota(ss.FanoutQuota{Bytes: 1000, MaxWait: time.Second})
+
+	//each broadcast is 500 bytes to 1 socket, so the first 2 are sent right away and the rest are queued
+	payload := make([]byte, 500-len("news")-3)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := serv.Broadcast("news", payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected the broadcasts over the quota to be queued, took %s", elapsed)
+	}
+	for i := 0; i < 4; i++ {
+		c.Expect("news")
+	}
+
+	serv.SetFanoutQuota(ss.FanoutQuota{Bytes: 1000, MaxWait: 10 * time.Millisecond})
+	serv.Broadcast("news", payload)
+	if err := serv.Broadcast("news", payload); err != ss.ErrFanoutQuota {
+		t.Errorf("expected %v once the queue wait is too long, got %v", ss.ErrFanoutQuota, err)
+	}
+}
+
+func TestSocketFanoutQuotaRefund(t *testing.T) {
+	serv := ss.NewServer()
+	serv.SetFanoutQuota(ss.FanoutQuota{Recipients: 20})
+	serv.SetSocketFanoutQuota(ss.FanoutQuota{Recipients: 2})
+	serv.On("shout", func(s *ss.Socket, data []byte) {
+		if err := s.Socketcast(s.ID(), "news", ""); err != nil {
+			s.Emit("rejected", err.Error())
+		}
+	})
+
+	srv := sstest.NewServer(t, serv)
+	c := srv.Dial()
+
+	//use up the server's quota, so the server rejects the socket's casts
+	for i := 0; i < 20; i++ {
+		if err := srv.Socketcast(c.ID(), "drain", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		c.Emit("shout", "")
+		c.Expect("rejected")
+	}
+
+	//the server's quota recovers much faster than the socket's, so the socket can only send 2 more casts
+	//if it was never charged for the rejected ones
+	time.Sleep(150 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		c.Emit("shout", "")
+		c.Expect("news")
+	}
+	c.ExpectNone("rejected", 20*time.Millisecond)
+
+	//which leaves the socket's quota empty
+	c.Emit("shout", "")
+	c.Expect("rejected")
+}
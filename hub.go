@@ -1,11 +1,19 @@
 package ss
 
+import (
+	"github.com/raz-varren/log"
+)
+
 type socketHub struct {
 	sockets map[string]*Socket
 	rooms   map[string]*room
 
 	shutdownCh       chan bool
 	socketList       chan []*Socket
+	getSocketCh      chan *socketRequest
+	listSocketsCh    chan chan []*Socket
+	listRoomsCh      chan chan map[string][]string
+	countCh          chan *countRequest
 	addCh            chan *Socket
 	delCh            chan *Socket
 	joinRoomCh       chan *joinRequest
@@ -14,6 +22,8 @@ type socketHub struct {
 	broomcastCh      chan *RoomMsg //for passing data from the backend
 	broadcastCh      chan *BroadcastMsg
 	bbroadcastCh     chan *BroadcastMsg
+	controlCh        chan *ControlMsg
+	bcontrolCh       chan *ControlMsg //for passing control messages from the backend
 	multihomeEnabled bool
 	multihomeBackend MultihomeBackend
 }
@@ -33,6 +43,17 @@ type leaveRequest struct {
 	socket   *Socket
 }
 
+type countRequest struct {
+	roomName string
+	all      bool
+	resp     chan int
+}
+
+type socketRequest struct {
+	socketID string
+	resp     chan *Socket
+}
+
 //RoomMsg represents an event to be dispatched to a room of sockets
 type RoomMsg struct {
 	RoomName  string
@@ -46,6 +67,28 @@ type BroadcastMsg struct {
 	Data      interface{}
 }
 
+//ControlAction is an action that can be performed on a Socket using a ControlMsg
+type ControlAction string
+
+const (
+	//ControlDisconnect closes the Socket
+	ControlDisconnect ControlAction = "disconnect"
+
+	//ControlJoin adds the Socket to ControlMsg.RoomName
+	ControlJoin ControlAction = "join"
+
+	//ControlLeave removes the Socket from ControlMsg.RoomName
+	ControlLeave ControlAction = "leave"
+)
+
+//ControlMsg represents an action to be performed on a Socket, which may be connected
+//to any of the SocketServers sharing a MultihomeBackend
+type ControlMsg struct {
+	Action   ControlAction
+	SocketID string
+	RoomName string
+}
+
 func (h *socketHub) addSocket(s *Socket) {
 	h.addCh <- s
 }
@@ -70,6 +113,36 @@ func (h *socketHub) broadcast(b *BroadcastMsg) {
 	h.broadcastCh <- b
 }
 
+func (h *socketHub) control(c *ControlMsg) {
+	h.controlCh <- c
+}
+
+func (h *socketHub) getSocket(socketID string) *Socket {
+	r := &socketRequest{socketID, make(chan *Socket)}
+	h.getSocketCh <- r
+	return <-r.resp
+}
+
+func (h *socketHub) listSockets() []*Socket {
+	resp := make(chan []*Socket)
+	h.listSocketsCh <- resp
+	return <-resp
+}
+
+func (h *socketHub) listRooms() map[string][]string {
+	resp := make(chan map[string][]string)
+	h.listRoomsCh <- resp
+	return <-resp
+}
+
+//countRecipients returns the number of Sockets a Roomcast to roomName, or a Broadcast if all is true,
+//would be sent to, counting the MultihomeBackend as one more recipient if one is set
+func (h *socketHub) countRecipients(roomName string, all bool) int {
+	r := &countRequest{roomName, all, make(chan int)}
+	h.countCh <- r
+	return <-r.resp
+}
+
 func (h *socketHub) setMultihomeBackend(b MultihomeBackend) {
 	if h.multihomeEnabled {
 		return //can't have two backends... yet
@@ -82,6 +155,7 @@ func (h *socketHub) setMultihomeBackend(b MultihomeBackend) {
 
 	go h.multihomeBackend.BroadcastFromBackend(h.bbroadcastCh)
 	go h.multihomeBackend.RoomcastFromBackend(h.broomcastCh)
+	go h.multihomeBackend.ControlFromBackend(h.bcontrolCh)
 }
 
 func (h *socketHub) listen() {
@@ -105,30 +179,59 @@ func (h *socketHub) listen() {
 			}
 		case c := <-h.roomMsgCh:
 			if room, exists := h.rooms[c.RoomName]; exists {
-				for _, s := range room.sockets {
-					s.Emit(c.EventName, c.Data)
-				}
+				emitAll(room.sockets, c.EventName, c.Data)
 			}
 			if h.multihomeEnabled { //the room may exist on the other end
 				go h.multihomeBackend.RoomcastToBackend(c)
 			}
 		case c := <-h.broomcastCh:
 			if room, exists := h.rooms[c.RoomName]; exists {
-				for _, s := range room.sockets {
-					s.Emit(c.EventName, c.Data)
-				}
+				emitAll(room.sockets, c.EventName, c.Data)
 			}
 		case c := <-h.broadcastCh:
-			for _, s := range h.sockets {
-				s.Emit(c.EventName, c.Data)
-			}
+			emitAll(h.sockets, c.EventName, c.Data)
 			if h.multihomeEnabled {
 				go h.multihomeBackend.BroadcastToBackend(c)
 			}
 		case c := <-h.bbroadcastCh:
+			emitAll(h.sockets, c.EventName, c.Data)
+		case c := <-h.controlCh:
+			if s, exists := h.sockets[c.SocketID]; exists {
+				go s.control(c)
+			} else if h.multihomeEnabled { //the socket may be on the other end
+				go h.multihomeBackend.ControlToBackend(c)
+			}
+		case c := <-h.bcontrolCh:
+			if s, exists := h.sockets[c.SocketID]; exists {
+				go s.control(c)
+			}
+		case c := <-h.getSocketCh:
+			c.resp <- h.sockets[c.socketID]
+		case c := <-h.listSocketsCh:
+			socketList := make([]*Socket, 0, len(h.sockets))
 			for _, s := range h.sockets {
-				s.Emit(c.EventName, c.Data)
+				socketList = append(socketList, s)
+			}
+			c <- socketList
+		case c := <-h.listRoomsCh:
+			roomList := make(map[string][]string, len(h.rooms))
+			for name, room := range h.rooms {
+				for id := range room.sockets {
+					roomList[name] = append(roomList[name], id)
+				}
+			}
+			c <- roomList
+		case c := <-h.countCh:
+			n := 0
+			if c.all {
+				n = len(h.sockets)
+			} else if room, exists := h.rooms[c.roomName]; exists {
+				n = len(room.sockets)
+			}
+			if h.multihomeEnabled {
+				n++
 			}
+			c.resp <- n
 		case _ = <-h.shutdownCh:
 			var socketList []*Socket
 			for _, s := range h.sockets {
@@ -139,10 +242,27 @@ func (h *socketHub) listen() {
 	}
 }
 
+//emitAll encodes the event once and sends it to every socket in sockets
+func emitAll(sockets map[string]*Socket, eventName string, data interface{}) {
+	d, msgType, err := emitData(eventName, data)
+	if err != nil {
+		log.Err.Println(err)
+		return
+	}
+
+	for _, s := range sockets {
+		s.send(msgType, d)
+	}
+}
+
 func newHub() *socketHub {
 	h := &socketHub{
 		shutdownCh:       make(chan bool),
 		socketList:       make(chan []*Socket),
+		getSocketCh:      make(chan *socketRequest),
+		listSocketsCh:    make(chan chan []*Socket),
+		listRoomsCh:      make(chan chan map[string][]string),
+		countCh:          make(chan *countRequest),
 		sockets:          make(map[string]*Socket),
 		rooms:            make(map[string]*room),
 		addCh:            make(chan *Socket),
@@ -153,6 +273,8 @@ func newHub() *socketHub {
 		broomcastCh:      make(chan *RoomMsg),
 		broadcastCh:      make(chan *BroadcastMsg),
 		bbroadcastCh:     make(chan *BroadcastMsg),
+		controlCh:        make(chan *ControlMsg),
+		bcontrolCh:       make(chan *ControlMsg),
 		multihomeEnabled: false,
 	}
 
@@ -162,11 +284,16 @@ func newHub() *socketHub {
 }
 
 //MultihomeBackend is an interface for implementing a mechanism
-//to syncronize Broadcasts and Roomcasts to multiple SocketServers
+//to syncronize Broadcasts, Roomcasts, and socket control messages to multiple SocketServers
 //running separate machines.
 //
 //Sacrificial-Socket provides a MultihomeBackend for use with MongoDB
 //in sacrificial-socket/backend
+//
+//Methods may be added to MultihomeBackend as new kinds of messages need to be syncronized,
+//which breaks the build of third party implementations until they implement them. ControlToBackend
+//and ControlFromBackend were added this way, a backend that does not support socket control
+//messages can implement them as no-ops, ControlFromBackend may simply return.
 type MultihomeBackend interface {
 	//Init is called as soon as the MultihomeBackend is
 	//registered using SocketServer.SetMultihomeBackend
@@ -206,4 +333,19 @@ type MultihomeBackend interface {
 	//r consumes a RoomMsg and dispatches it to all sockets
 	//that are members the specified room
 	RoomcastFromBackend(r chan<- *RoomMsg)
+
+	//ControlToBackend is called everytime a ControlMsg is sent
+	//for a socket that is not connected to this server
+	//
+	//ControlToBackend must be safe for concurrent use by multiple
+	//go routines
+	ControlToBackend(*ControlMsg)
+
+	//ControlFromBackend is called once and only once as a go routine as
+	//soon as the MultihomeBackend is registered using
+	//SocketServer.SetMultihomeBackend
+	//
+	//c consumes a ControlMsg and performs its action on the specified
+	//socket, if that socket is connected to this server
+	ControlFromBackend(c chan<- *ControlMsg)
 }
@@ -0,0 +1,85 @@
+package ss_test
+
+import (
+	"fmt"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMultipleListeners(t *testing.T) {
+	serv := ss.NewServer()
+
+	calls := make(chan string, 4)
+	serv.OnConnect(func(s *ss.Socket) { calls <- "connect 1" })
+	serv.OnConnect(func(s *ss.Socket) { calls <- "connect 2" })
+	serv.OnDisconnect(func(s *ss.Socket) { calls <- "disconnect 1" })
+	serv.OnDisconnect(func(s *ss.Socket) { calls <- "disconnect 2" })
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Close()
+
+	for _, expected := range []string{"connect 1", "connect 2", "disconnect 1", "disconnect 2"} {
+		select {
+		case call := <-calls:
+			if call != expected {
+				t.Errorf("expected %s, got %s", expected, call)
+			}
+		case <-time.After(sstest.DefaultTimeout):
+			t.Fatalf("expected %s", expected)
+		}
+	}
+}
+
+func TestOffAndOnce(t *testing.T) {
+	serv := ss.NewServer()
+	serv.On("ping", func(s *ss.Socket, data []byte) {
+		s.Emit("pong", "")
+	})
+	serv.Once("claim", func(s *ss.Socket, data []byte) {
+		s.Emit("claimed", s.ID())
+	})
+
+	srv := sstest.NewServer(t, serv)
+	a, b := srv.Dial(), srv.Dial()
+
+	a.Emit("ping", "")
+	a.Expect("pong")
+
+	serv.Off("ping")
+	a.Emit("ping", "")
+	a.ExpectNone("pong", 20*time.Millisecond)
+
+	a.Emit("claim", "")
+	a.Expect("claimed")
+	b.Emit("claim", "")
+	b.ExpectNone("claimed", 20*time.Millisecond)
+}
+
+func TestRegisterWhileServing(t *testing.T) {
+	serv := ss.NewServer()
+	srv := sstest.NewServer(t, serv)
+	c := srv.Dial()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				name := fmt.Sprintf("plugin%d", j)
+				serv.On(name, func(s *ss.Socket, data []byte) {})
+				serv.OnConnect(func(s *ss.Socket) {})
+				serv.Off(name)
+			}
+		}(i)
+	}
+
+	for j := 0; j < 50; j++ {
+		c.Emit(fmt.Sprintf("plugin%d", j), "")
+	}
+	srv.Dial().Close()
+	wg.Wait()
+}
@@ -0,0 +1,97 @@
+package ss
+
+import (
+	"github.com/gorilla/websocket"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	//CauseIdleTimeout means the client did not emit anything for longer than the idle timeout, see SocketServer.SetIdleTimeout
+	CauseIdleTimeout DisconnectCause = "idle timeout"
+
+	//CauseMaxAge means the Socket reached its maximum age, see SocketServer.SetMaxConnectionAge
+	CauseMaxAge DisconnectCause = "max connection age"
+)
+
+//SetIdleTimeout makes the SocketServer close Sockets that have not sent anything for timeout. Websocket pongs
+//answering keepalive pings do not count, so idle clients are closed even while their connection is healthy.
+//Idle Sockets receive a close frame with the websocket.CloseNormalClosure code and the text "idle timeout",
+//and are disconnected with the CauseIdleTimeout reason. A timeout of 0, the default, disables the idle timeout.
+//
+//SetIdleTimeout only affects Sockets that connect after it is called.
+func (serv *SocketServer) SetIdleTimeout(timeout time.Duration) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.idleTimeout = timeout
+}
+
+//SetMaxConnectionAge makes the SocketServer close Sockets once they have been connected for age, plus a random
+//duration of up to jitter so clients that connected together do not all reconnect at once. This makes clients
+//reconnect and spread out over SocketServers added since they first connected. Sockets receive a close frame
+//with the websocket.CloseGoingAway code and the text "max connection age", and are disconnected with the
+//CauseMaxAge reason. An age of 0, the default, disables the limit.
+//
+//SetMaxConnectionAge only affects Sockets that connect after it is called.
+func (serv *SocketServer) SetMaxConnectionAge(age, jitter time.Duration) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.maxAge = age
+	serv.maxAgeJitter = jitter
+}
+
+//touch records that s received something from the client
+func (s *Socket) touch() {
+	atomic.StoreInt64(&s.lastRead, time.Now().UnixNano())
+}
+
+//watchLifetime closes s once it has been idle for idleTimeout or connected for maxAge,
+//either of which may be 0 to disable it
+func (s *Socket) watchLifetime(idleTimeout, maxAge time.Duration) {
+	var idle, age <-chan time.Time
+
+	if idleTimeout > 0 {
+		s.touch()
+		t := time.NewTimer(idleTimeout)
+		defer t.Stop()
+		idle = t.C
+	}
+
+	if maxAge > 0 {
+		t := time.NewTimer(maxAge)
+		defer t.Stop()
+		age = t.C
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return
+
+		case <-age:
+			s.disconnect(CauseMaxAge, websocket.CloseGoingAway, "max connection age")
+			return
+
+		case <-idle:
+			since := time.Since(time.Unix(0, atomic.LoadInt64(&s.lastRead)))
+			if since >= idleTimeout {
+				s.disconnect(CauseIdleTimeout, websocket.CloseNormalClosure, "idle timeout")
+				return
+			}
+			idle = time.After(idleTimeout - since)
+		}
+	}
+}
+
+//lifetimeLimits returns the idle timeout and the maximum age, including its jitter, of a new Socket
+func (serv *SocketServer) lifetimeLimits() (time.Duration, time.Duration) {
+	serv.l.RLock()
+	defer serv.l.RUnlock()
+
+	maxAge := serv.maxAge
+	if maxAge > 0 && serv.maxAgeJitter > 0 {
+		maxAge += time.Duration(rand.Int63n(int64(serv.maxAgeJitter)))
+	}
+	return serv.idleTimeout, maxAge
+}
@@ -0,0 +1,46 @@
+package ss_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/sacrificial-socket"
+	"testing"
+	"time"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	srv, reasons := newReasonServer(t)
+	srv.SetIdleTimeout(60 * time.Millisecond)
+
+	c := srv.Dial()
+	for i := 0; i < 4; i++ {
+		time.Sleep(30 * time.Millisecond)
+		c.Emit("noop", "")
+	}
+	if c.Closed() {
+		t.Fatal("expected an active client to stay connected")
+	}
+
+	c.ExpectClosed()
+	expectReason(t, reasons, ss.CauseIdleTimeout, websocket.CloseNormalClosure)
+	if err, ok := c.Err().(*websocket.CloseError); !ok || err.Text != "idle timeout" {
+		t.Errorf("expected the client to receive an idle timeout close frame, got %v", c.Err())
+	}
+}
+
+func TestMaxConnectionAge(t *testing.T) {
+	srv, reasons := newReasonServer(t)
+	srv.SetMaxConnectionAge(50*time.Millisecond, 20*time.Millisecond)
+
+	start := time.Now()
+	c := srv.Dial()
+	c.ExpectClosed()
+
+	if age := time.Since(start); age < 50*time.Millisecond {
+		t.Errorf("expected the connection to last at least 50ms, lasted %s", age)
+	}
+	expectReason(t, reasons, ss.CauseMaxAge, websocket.CloseGoingAway)
+	if err, ok := c.Err().(*websocket.CloseError); !ok || err.Code != websocket.CloseGoingAway {
+		t.Errorf("expected the client to receive a going away close frame, got %v", c.Err())
+	}
+}
+
@@ -0,0 +1,77 @@
+package ss
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/sacrificial-socket/ssproto"
+)
+
+//Message represents an event received from a client Socket
+type Message struct {
+	//EventName is the name of the event the client emitted
+	EventName string
+
+	//Type is the kind of payload the client sent. Clients that do not send
+	//a data type header will have Type set to ssproto.TypeBin if the payload
+	//arrived in a binary websocket message, and ssproto.TypeStr otherwise
+	Type ssproto.DataType
+
+	//Raw is the payload exactly as the client sent it
+	Raw []byte
+
+	//Params holds the parameters extracted from EventName when the event matched a
+	//pattern registered with SocketServer.OnPattern, otherwise it is nil
+	Params map[string]string
+}
+
+func newMessage(msgType int, frame []byte) (*Message, error) {
+	eventName, dataType, payload, err := ssproto.Decode(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	if dataType == ssproto.TypeUnknown {
+		dataType = ssproto.TypeStr
+		if msgType == websocket.BinaryMessage {
+			dataType = ssproto.TypeBin
+		}
+	}
+
+	return &Message{
+		EventName: eventName,
+		Type:      dataType,
+		Raw:       payload,
+	}, nil
+}
+
+//IsString returns true if the client sent a string payload
+func (m *Message) IsString() bool {
+	return m.Type == ssproto.TypeStr
+}
+
+//IsBinary returns true if the client sent a binary payload
+func (m *Message) IsBinary() bool {
+	return m.Type == ssproto.TypeBin
+}
+
+//IsJSON returns true if the client sent a JSON payload
+func (m *Message) IsJSON() bool {
+	return m.Type == ssproto.TypeJSON
+}
+
+//Param returns the pattern parameter named name, or an empty string if it was not set
+func (m *Message) Param(name string) string {
+	return m.Params[name]
+}
+
+//String returns the payload as a string
+func (m *Message) String() string {
+	return string(m.Raw)
+}
+
+//Unmarshal parses the payload as JSON and stores the result in the value pointed to by v.
+//It is not an error to call Unmarshal on a payload that was not sent as JSON, as long as the
+//payload itself is valid JSON.
+func (m *Message) Unmarshal(v interface{}) error {
+	return json.Unmarshal(m.Raw, v)
+}
@@ -0,0 +1,109 @@
+package ss
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+//OriginPolicy decides whether a websocket handshake request's Origin is allowed to connect
+//to the SocketServer. Return true to accept the request.
+type OriginPolicy func(r *http.Request) bool
+
+//SameOrigin is an OriginPolicy that only accepts requests whose Origin host matches the
+//request's Host header. This is the policy used if no other policy has been set.
+//
+//Requests without an Origin header are accepted, since they do not come from a browser.
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+//AnyOrigin is an OriginPolicy that accepts every request
+func AnyOrigin(r *http.Request) bool {
+	return true
+}
+
+//AllowOrigins returns an OriginPolicy that only accepts requests from the listed origins.
+//
+//An origin can be written with or without its scheme, "https://example.com" only matches
+//https pages while "example.com" matches any scheme. Ports must match exactly, "localhost:8080" does not
+//match "localhost". A leading "*." matches any subdomain, but not the domain itself, so "https://*.example.com"
+//matches "https://chat.example.com" and "https://a.b.example.com" but not "https://example.com".
+//
+//Requests without an Origin header are accepted, since they do not come from a browser.
+func AllowOrigins(origins ...string) OriginPolicy {
+	type pattern struct {
+		scheme   string
+		host     string
+		wildcard bool
+	}
+
+	patterns := make([]pattern, 0, len(origins))
+	for _, o := range origins {
+		var p pattern
+
+		o = strings.ToLower(strings.TrimSpace(o))
+		if i := strings.Index(o, "://"); i != -1 {
+			p.scheme, o = o[:i], o[i+3:]
+		}
+		if strings.HasPrefix(o, "*.") {
+			p.wildcard, o = true, o[1:] //keep the dot so "badexample.com" can't match
+		}
+		p.host = strings.TrimSuffix(o, "/")
+
+		patterns = append(patterns, p)
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		u, err := url.Parse(strings.ToLower(origin))
+		if err != nil || u.Host == "" {
+			return false
+		}
+
+		for _, p := range patterns {
+			if p.scheme != "" && p.scheme != u.Scheme {
+				continue
+			}
+			if p.wildcard && strings.HasSuffix(u.Host, p.host) {
+				return true
+			}
+			if !p.wildcard && u.Host == p.host {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//SetOriginPolicy sets the OriginPolicy used to accept or reject websocket handshakes.
+//Rejected requests receive a 403 Forbidden response, are logged, and are counted by
+//SocketServer.RejectedOrigins.
+//
+//The OriginPolicy takes the place of the websocket.Upgrader's CheckOrigin function. If no OriginPolicy
+//is set, the upgrader's CheckOrigin is used if it has one, otherwise SameOrigin is used.
+func (serv *SocketServer) SetOriginPolicy(p OriginPolicy) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.originPolicy = p
+}
+
+//RejectedOrigins returns the number of websocket handshakes rejected by the
+//SocketServer's OriginPolicy
+func (serv *SocketServer) RejectedOrigins() uint64 {
+	return atomic.LoadUint64(&serv.rejectedOrigins)
+}
@@ -0,0 +1,89 @@
+package ss_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowOrigins(t *testing.T) {
+	policy := ss.AllowOrigins("https://example.com", "*.example.org", "https://*.example.net", "localhost:8080")
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"https://example.com", true},
+		{"HTTPS://EXAMPLE.COM", true},
+		{"http://example.com", false},
+		{"https://chat.example.com", false},
+		{"http://chat.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://badexample.org", false},
+		{"https://chat.example.net", true},
+		{"http://chat.example.net", false},
+		{"http://localhost:8080", true},
+		{"http://localhost", false},
+		{"null", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/socket", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if allowed := policy(r); allowed != tt.allowed {
+			t.Errorf("origin %q: expected allowed to be %v, got %v", tt.origin, tt.allowed, allowed)
+		}
+	}
+}
+
+func TestOriginPolicy(t *testing.T) {
+	serv := ss.NewServer()
+	srv := sstest.NewServer(t, serv)
+
+	cross := http.Header{"Origin": []string{"https://example.com"}}
+
+	//same origin is the default
+	_, err := sstest.Dial(srv.URL, cross)
+	if err != websocket.ErrBadHandshake {
+		t.Fatalf("expected a cross origin handshake to fail, got %v", err)
+	}
+	if n := serv.RejectedOrigins(); n != 1 {
+		t.Errorf("expected 1 rejected origin, got %d", n)
+	}
+
+	serv.SetOriginPolicy(ss.AllowOrigins("https://example.com"))
+	srv.DialHeader(cross).Close()
+
+	serv.SetOriginPolicy(func(r *http.Request) bool { return false })
+	_, err = sstest.Dial(srv.URL, cross)
+	if err != websocket.ErrBadHandshake {
+		t.Fatalf("expected the custom policy to reject the handshake, got %v", err)
+	}
+	if n := serv.RejectedOrigins(); n != 2 {
+		t.Errorf("expected 2 rejected origins, got %d", n)
+	}
+}
+
+func TestSetUpgraderSubProtocol(t *testing.T) {
+	serv := ss.NewServer()
+	serv.SetUpgrader(&websocket.Upgrader{})
+	srv := sstest.NewServer(t, serv)
+
+	d := &websocket.Dialer{Subprotocols: []string{ss.SubProtocol}}
+	ws, _, err := d.Dial(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	if p := ws.Subprotocol(); p != ss.SubProtocol {
+		t.Errorf("expected the %s sub protocol to be negotiated, got %q", ss.SubProtocol, p)
+	}
+}
@@ -0,0 +1,82 @@
+package ss
+
+import (
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/log"
+	"runtime/debug"
+)
+
+const (
+	//ConnectEvent is the event name passed to the OnError function when an OnConnect function panics
+	ConnectEvent = "__ss_connect"
+
+	//DisconnectEvent is the event name passed to the OnError function when an OnDisconnect
+	//or OnDisconnectReason function panics
+	DisconnectEvent = "__ss_disconnect"
+)
+
+//PanicError is the error passed to the OnError function when an event function panics
+type PanicError struct {
+	//Value is the value the event function panicked with
+	Value interface{}
+
+	//Stack is the stack trace of the goroutine that panicked
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprint("panic: ", e.Value)
+}
+
+//OnError registers a function to be called when an event function panics. Panics in functions registered
+//with On, OnMessage, OnPattern, OnAny, OnUnknown, OnStream, and their Socket equivalents are passed along
+//with the event's name. Panics in OnConnect functions are passed with ConnectEvent, and panics in OnDisconnect
+//and OnDisconnectReason functions with DisconnectEvent. err is always a *PanicError, which holds the stack trace.
+//
+//Panics are always recovered, if no OnError function is registered they are logged along with their stack trace.
+//Calling OnError again replaces the previous function.
+func (serv *SocketServer) OnError(handleFunc func(s *Socket, eventName string, err error)) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.onErrorFunc = handleFunc
+}
+
+//SetClosePanicking makes the SocketServer close Sockets whose event functions panic, with the
+//websocket.CloseInternalServerErr close code and the CausePanic disconnect reason.
+func (serv *SocketServer) SetClosePanicking(enabled bool) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.closePanicking = enabled
+}
+
+//call calls f, recovering any panic and reporting it as a panic in the event function for eventName
+func (serv *SocketServer) call(s *Socket, eventName string, f func()) {
+	defer serv.recoverPanic(s, eventName)
+	f()
+}
+
+//recoverPanic must be deferred, it recovers a panic in the event function for eventName
+//and passes it to the OnError function
+func (serv *SocketServer) recoverPanic(s *Socket, eventName string) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	err := &PanicError{Value: v, Stack: debug.Stack()}
+
+	serv.l.RLock()
+	onError := serv.onErrorFunc
+	closePanicking := serv.closePanicking
+	serv.l.RUnlock()
+
+	if onError != nil {
+		onError(s, eventName, err)
+	} else {
+		log.Err.Println(s.ID(), eventName, "event function", err.Error()+"\n"+string(err.Stack))
+	}
+
+	if closePanicking {
+		s.disconnect(CausePanic, websocket.CloseInternalServerErr, "internal error")
+	}
+}
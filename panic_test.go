@@ -0,0 +1,75 @@
+package ss_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type panicReport struct {
+	event string
+	err   *ss.PanicError
+}
+
+func TestOnError(t *testing.T) {
+	reports := make(chan panicReport, 4)
+
+	serv := ss.NewServer()
+	serv.OnError(func(s *ss.Socket, eventName string, err error) {
+		reports <- panicReport{eventName, err.(*ss.PanicError)}
+	})
+	serv.On("crash", func(s *ss.Socket, data []byte) {
+		var attrs map[string]string
+		attrs["boom"] = "" //nil map
+	})
+	serv.On("ping", func(s *ss.Socket, data []byte) {
+		s.Emit("pong", "")
+	})
+	serv.OnDisconnect(func(s *ss.Socket) {
+		panic("disconnect")
+	})
+
+	srv := sstest.NewServer(t, serv)
+	c := srv.Dial()
+
+	expect := func(event string) {
+		t.Helper()
+		select {
+		case r := <-reports:
+			if r.event != event || !strings.Contains(string(r.err.Stack), "panic") {
+				t.Errorf("expected a panic in %s with a stack, got %s %v", event, r.event, r.err)
+			}
+		case <-time.After(sstest.DefaultTimeout):
+			t.Fatalf("expected a panic in %s", event)
+		}
+	}
+
+	c.Emit("crash", "")
+	expect("crash")
+
+	c.Emit("ping", "")
+	c.Expect("pong")
+
+	c.Close()
+	expect(ss.DisconnectEvent)
+}
+
+func TestClosePanicking(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnError(func(s *ss.Socket, eventName string, err error) {})
+	serv.SetClosePanicking(true)
+	serv.On("crash", func(s *ss.Socket, data []byte) {
+		panic("crash")
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("crash", "")
+	c.ExpectClosed()
+
+	if err, ok := c.Err().(*websocket.CloseError); !ok || err.Code != websocket.CloseInternalServerErr {
+		t.Errorf("expected the client to receive an internal error close code, got %v", c.Err())
+	}
+}
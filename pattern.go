@@ -0,0 +1,151 @@
+package ss
+
+import (
+	"regexp"
+	"strings"
+)
+
+//eventPattern is an event function registered with SocketServer.OnPattern
+type eventPattern struct {
+	pattern string
+	re      *regexp.Regexp
+	names   []string
+	event   *event
+}
+
+//compilePattern turns an OnPattern pattern into a regular expression and the names of its parameters.
+//Parameter names must be unique, since each one is stored under its name in Message.Params.
+func compilePattern(pattern string) (*regexp.Regexp, []string) {
+	var (
+		expr  strings.Builder
+		names []string
+	)
+	expr.WriteString("^")
+
+	rest := pattern
+	for rest != "" {
+		i := strings.IndexAny(rest, "*{")
+		if i == -1 {
+			expr.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		expr.WriteString(regexp.QuoteMeta(rest[:i]))
+
+		if rest[i] == '*' {
+			if hasName(names, "*") {
+				panic("ss: more than one * in event pattern " + pattern)
+			}
+			expr.WriteString("(.*)")
+			names = append(names, "*")
+			rest = rest[i+1:]
+			continue
+		}
+
+		end := strings.IndexByte(rest[i:], '}')
+		if end == -1 {
+			panic("ss: unclosed parameter in event pattern " + pattern)
+		}
+		name := rest[i+1 : i+end]
+		if name == "" || strings.ContainsAny(name, "{*/:") {
+			panic("ss: bad parameter name in event pattern " + pattern)
+		}
+		if hasName(names, name) {
+			panic("ss: duplicate parameter " + name + " in event pattern " + pattern)
+		}
+
+		expr.WriteString("([^/:]+)")
+		names = append(names, name)
+		rest = rest[i+end+1:]
+	}
+
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()), names
+}
+
+func hasName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+//OnPattern registers an event function to be called for every event whose name matches pattern.
+//A "*" in pattern matches any run of characters, and a "{name}" matches one or more characters other
+//than "/" and ":". The text matched by each parameter is available from Message.Params, the "*" parameter
+//is stored under the "*" key.
+//
+//	serv.OnPattern("room/{id}/msg", func(s *ss.Socket, msg *ss.Message) {
+//		s.Roomcast(msg.Param("id"), "msg", msg.String())
+//	})
+//	serv.OnPattern("chat:*", func(s *ss.Socket, msg *ss.Message) {
+//		log.Println("chat event", msg.Param("*"))
+//	})
+//
+//Event functions registered for an exact event name with On, OnMessage, or Socket.On take precedence over
+//patterns. Patterns are tried in the order they were registered. Registering the same pattern again replaces
+//its event function, and Off removes it. OnPattern panics if pattern has an unclosed or empty "{}" parameter,
+//more than one "*", or the same parameter name more than once.
+func (serv *SocketServer) OnPattern(pattern string, handleFunc func(*Socket, *Message)) {
+	re, names := compilePattern(pattern)
+	p := &eventPattern{pattern, re, names, &event{pattern, handleFunc}}
+
+	serv.l.Lock()
+	defer serv.l.Unlock()
+
+	for i, existing := range serv.patterns {
+		if existing.pattern == pattern {
+			serv.patterns[i] = p
+			return
+		}
+	}
+	serv.patterns = append(serv.patterns, p)
+}
+
+//OnAny registers an event function to be called for every event received by the SocketServer, right before
+//the event's own event function. It is called for events without an event function as well, but not for
+//events rejected by an ACL or schema. OnAny may be called more than once.
+func (serv *SocketServer) OnAny(handleFunc func(*Socket, *Message)) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.onAnyFuncs = append(serv.onAnyFuncs, handleFunc)
+}
+
+//OnUnknown registers an event function to be called for events that do not match any event function or
+//pattern, which would otherwise be dropped. Calling OnUnknown again replaces the previous event function.
+func (serv *SocketServer) OnUnknown(handleFunc func(*Socket, *Message)) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.onUnknownFunc = &event{"", handleFunc}
+}
+
+//lookup finds the event function for msg, trying s's event functions, then the SocketServer's, then its
+//patterns. If a pattern matches, its parameters are stored in msg.Params and the pattern is returned as well.
+func (serv *SocketServer) lookup(s *Socket, msg *Message) (*event, string, bool) {
+	if e, exists := s.getEvent(msg.EventName); exists {
+		return e, "", true
+	}
+
+	serv.l.RLock()
+	defer serv.l.RUnlock()
+
+	if e, exists := serv.events[msg.EventName]; exists {
+		return e, "", true
+	}
+
+	for _, p := range serv.patterns {
+		match := p.re.FindStringSubmatch(msg.EventName)
+		if match == nil {
+			continue
+		}
+
+		msg.Params = make(map[string]string, len(p.names))
+		for i, name := range p.names {
+			msg.Params[name] = match[i+1]
+		}
+		return p.event, p.pattern, true
+	}
+
+	return nil, "", false
+}
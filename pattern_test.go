@@ -0,0 +1,101 @@
+package ss_test
+
+import (
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"testing"
+	"time"
+)
+
+func TestOnPattern(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnPattern("room/{id}/msg", func(s *ss.Socket, msg *ss.Message) {
+		s.Emit("matched", "room "+msg.Param("id"))
+	})
+	serv.OnPattern("chat:*", func(s *ss.Socket, msg *ss.Message) {
+		s.Emit("matched", "chat "+msg.Param("*"))
+	})
+	serv.On("chat:exact", func(s *ss.Socket, data []byte) {
+		s.Emit("matched", "exact")
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+
+	tests := []struct {
+		event    string
+		expected string
+	}{
+		{"room/lobby/msg", "room lobby"},
+		{"chat:join", "chat join"},
+		{"chat:", "chat "},
+		{"chat:exact", "exact"},
+		{"room//msg", ""},
+		{"room/a/b/msg", ""},
+		{"room/lobby/msgs", ""},
+	}
+
+	for _, tt := range tests {
+		c.Emit(tt.event, "")
+		if tt.expected == "" {
+			c.ExpectNone("matched", 20*time.Millisecond)
+			continue
+		}
+		if got := c.Expect("matched").String(); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.event, tt.expected, got)
+		}
+	}
+
+	serv.Off("chat:*")
+	c.Emit("chat:join", "")
+	c.ExpectNone("matched", 20*time.Millisecond)
+}
+
+func TestOnAnyAndUnknown(t *testing.T) {
+	serv := ss.NewServer()
+	serv.On("known", func(s *ss.Socket, data []byte) {
+		s.Emit("handled", "known")
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+
+	serv.OnAny(func(s *ss.Socket, msg *ss.Message) {
+		s.Emit("any", msg.EventName)
+	})
+
+	c.Emit("known", "")
+	if got := c.Expect("any").String(); got != "known" {
+		t.Errorf("expected OnAny to see known, got %s", got)
+	}
+	c.Expect("handled")
+
+	c.Emit("mystery", "")
+	if got := c.Expect("any").String(); got != "mystery" {
+		t.Errorf("expected OnAny to see mystery, got %s", got)
+	}
+	c.ExpectNone("handled", 20*time.Millisecond)
+
+	serv.OnUnknown(func(s *ss.Socket, msg *ss.Message) {
+		s.Emit("handled", "unknown "+msg.EventName)
+	})
+	c.Emit("mystery", "")
+	c.Expect("any")
+	if got := c.Expect("handled").String(); got != "unknown mystery" {
+		t.Errorf("expected OnUnknown to handle mystery, got %s", got)
+	}
+}
+
+func TestOnPatternInvalid(t *testing.T) {
+	serv := ss.NewServer()
+	h := func(s *ss.Socket, msg *ss.Message) {}
+
+	for _, pattern := range []string{"room/{id", "room/{}", "room/{a:b}", "chat:*:*", "{id}/{id}"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected OnPattern to panic on %q", pattern)
+				}
+			}()
+			serv.OnPattern(pattern, h)
+		}()
+	}
+}
@@ -0,0 +1,167 @@
+package ss
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/log"
+	"time"
+)
+
+const (
+	//CodeRateLimited is the EventError code sent when an event is rejected by a RateLimit with the RateLimitError policy
+	CodeRateLimited = "rate_limited"
+
+	//CauseRateLimited means the Socket exceeded a RateLimit with the RateLimitDisconnect policy
+	CauseRateLimited DisconnectCause = "rate limited"
+)
+
+//RateLimitPolicy decides what happens to events that exceed a RateLimit
+type RateLimitPolicy int
+
+const (
+	//RateLimitDrop silently drops events over the limit
+	RateLimitDrop RateLimitPolicy = iota
+
+	//RateLimitDelay stops reading from the Socket until the event is within the limit, slowing the client down.
+	//Time spent delayed does not count towards the ping timeout set with SocketServer.SetPingInterval.
+	RateLimitDelay
+
+	//RateLimitError drops events over the limit and emits an ErrorEvent with the CodeRateLimited code to the client
+	RateLimitError
+
+	//RateLimitDisconnect closes the Socket with the websocket.ClosePolicyViolation code and the CauseRateLimited reason
+	RateLimitDisconnect
+)
+
+//RateLimit is a token bucket limit on the events a single Socket may emit
+type RateLimit struct {
+	//Rate is the number of events allowed per second
+	Rate float64
+
+	//Burst is the number of events that may be emitted at once after the Socket has been quiet for a while.
+	//A Burst less than 1 is treated as 1.
+	Burst int
+
+	//Policy decides what happens to events over the limit
+	Policy RateLimitPolicy
+}
+
+//SetRateLimit sets the RateLimit shared by all the events emitted by each Socket. Every Socket has its own
+//token bucket. A RateLimit with a Rate of 0, the default, disables the limit.
+//
+//Opening a stream counts as an event named after the stream's event, streams over the limit are aborted.
+//The chunk, ack, end, and abort frames of streams are not limited, a transfer sends a chunk and an ack for
+//every StreamChunkSize bytes, which would quickly exceed any limit meant for events. They are bounded by
+//StreamWindow and SetMaxStreams instead, and frames for streams that are not open are dropped.
+func (serv *SocketServer) SetRateLimit(rl RateLimit) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.rateLimit = rl
+}
+
+//SetEventRateLimit sets the RateLimit of events named eventName, which applies on top of the limit set with
+//SetRateLimit. Every Socket has its own token bucket for each eventName. A RateLimit with a Rate of 0 removes the limit.
+func (serv *SocketServer) SetEventRateLimit(eventName string, rl RateLimit) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+
+	if rl.Rate <= 0 {
+		delete(serv.eventRateLimits, eventName)
+		return
+	}
+	serv.eventRateLimits[eventName] = &rl
+}
+
+//tokenBucket is a token bucket, it is only used by a Socket's read loop so it needs no locking
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+//take takes a token from b, returning how long the caller must wait for the token to be available.
+//A token is only taken if the wait is 0, unless wait is true.
+func (b *tokenBucket) take(rl *RateLimit, now time.Time, wait bool) time.Duration {
+	burst := float64(rl.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * rl.Rate
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	delay := time.Duration((1 - b.tokens) / rl.Rate * float64(time.Second))
+	if wait {
+		b.tokens--
+	}
+	return delay
+}
+
+//rateLimiter holds the token buckets of a Socket
+type rateLimiter struct {
+	all    tokenBucket
+	events map[string]*tokenBucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{events: make(map[string]*tokenBucket)}
+}
+
+//limit applies the SocketServer's RateLimits to msg. It returns false if msg must not be dispatched.
+func (serv *SocketServer) limit(s *Socket, rl *rateLimiter, msg *Message) bool {
+	serv.l.RLock()
+	all := serv.rateLimit
+	ev, hasEvent := serv.eventRateLimits[msg.EventName]
+	serv.l.RUnlock()
+
+	if all.Rate > 0 && !serv.applyLimit(s, &rl.all, &all, msg) {
+		return false
+	}
+
+	if !hasEvent {
+		return true
+	}
+
+	b, exists := rl.events[msg.EventName]
+	if !exists {
+		b = &tokenBucket{}
+		rl.events[msg.EventName] = b
+	}
+	return serv.applyLimit(s, b, ev, msg)
+}
+
+func (serv *SocketServer) applyLimit(s *Socket, b *tokenBucket, rl *RateLimit, msg *Message) bool {
+	delay := b.take(rl, time.Now(), rl.Policy == RateLimitDelay)
+	if delay == 0 {
+		return true
+	}
+
+	switch rl.Policy {
+	case RateLimitDelay:
+		select {
+		case <-time.After(delay):
+			return true
+		case <-s.done:
+			return false
+		}
+	case RateLimitError:
+		log.Debug.Println(s.ID(), "rate limited", msg.EventName)
+		s.EmitError(msg.EventName, CodeRateLimited, "too many events, retry in "+delay.Round(time.Millisecond).String())
+	case RateLimitDisconnect:
+		log.Debug.Println(s.ID(), "disconnected for exceeding the rate limit of", msg.EventName)
+		s.disconnect(CauseRateLimited, websocket.ClosePolicyViolation, "rate limited")
+	default:
+		log.Debug.Println(s.ID(), "rate limited", msg.EventName)
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package ss_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"testing"
+	"time"
+)
+
+func newCountingServer(t *testing.T) (*sstest.Server, chan string) {
+	handled := make(chan string, 100)
+
+	serv := ss.NewServer()
+	serv.On("shout", func(s *ss.Socket, data []byte) {
+		handled <- "shout"
+	})
+	serv.On("whisper", func(s *ss.Socket, data []byte) {
+		handled <- "whisper"
+	})
+	return sstest.NewServer(t, serv), handled
+}
+
+func count(handled chan string, wait time.Duration) map[string]int {
+	counts := make(map[string]int)
+	deadline := time.After(wait)
+	for {
+		select {
+		case ev := <-handled:
+			counts[ev]++
+		case <-deadline:
+			return counts
+		}
+	}
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	srv, handled := newCountingServer(t)
+	srv.SetRateLimit(ss.RateLimit{Rate: 1, Burst: 7})
+	srv.SetEventRateLimit("shout", ss.RateLimit{Rate: 1, Burst: 2})
+
+	c := srv.Dial()
+	for i := 0; i < 4; i++ {
+		c.Emit("shout", "")
+	}
+	for i := 0; i < 4; i++ {
+		c.Emit("whisper", "")
+	}
+
+	counts := count(handled, 100*time.Millisecond)
+	if counts["shout"] != 2 || counts["whisper"] != 3 {
+		t.Errorf("expected 2 shouts and 3 whispers, got %v", counts)
+	}
+}
+
+func TestRateLimitDelayKeepalive(t *testing.T) {
+	srv, handled := newCountingServer(t)
+	srv.SetPingInterval(20*time.Millisecond, 30*time.Millisecond)
+	srv.SetRateLimit(ss.RateLimit{Rate: 4, Burst: 1, Policy: ss.RateLimitDelay})
+
+	c := srv.Dial()
+	s, _ := srv.GetSocket(c.ID())
+
+	//the second shout is delayed for much longer than the ping deadline
+	c.Emit("shout", "")
+	c.Emit("shout", "")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-handled:
+		case <-time.After(sstest.DefaultTimeout):
+			t.Fatalf("expected 2 shouts, got %d", i)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if reason := s.DisconnectReason(); reason != nil || c.Closed() {
+		t.Errorf("expected the delayed socket to stay connected, got %+v", reason)
+	}
+}
+
+func TestRateLimitError(t *testing.T) {
+	srv, handled := newCountingServer(t)
+	srv.SetEventRateLimit("shout", ss.RateLimit{Rate: 1, Burst: 1, Policy: ss.RateLimitError})
+
+	c := srv.Dial()
+	c.Emit("shout", "")
+	c.Emit("shout", "")
+
+	var e ss.EventError
+	err := c.Expect(ss.ErrorEvent).Unmarshal(&e)
+	if err != nil || e.Event != "shout" || e.Code != ss.CodeRateLimited {
+		t.Errorf("expected a rate_limited error for shout, got %+v (%v)", e, err)
+	}
+	if counts := count(handled, 50*time.Millisecond); counts["shout"] != 1 {
+		t.Errorf("expected 1 shout, got %v", counts)
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	srv, handled := newCountingServer(t)
+	srv.SetRateLimit(ss.RateLimit{Rate: 50, Burst: 1, Policy: ss.RateLimitDelay})
+
+	c := srv.Dial()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		c.Emit("shout", "")
+	}
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-handled:
+		case <-time.After(sstest.DefaultTimeout):
+			t.Fatalf("expected 5 delayed shouts, got %d", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("expected the shouts to be spread over at least 70ms, took %s", elapsed)
+	}
+}
+
+func TestRateLimitDisconnect(t *testing.T) {
+	srv, _ := newCountingServer(t)
+	srv.SetRateLimit(ss.RateLimit{Rate: 1, Burst: 1, Policy: ss.RateLimitDisconnect})
+
+	c := srv.Dial()
+	c.Emit("shout", "")
+	c.Emit("shout", "")
+	c.ExpectClosed()
+
+	if err, ok := c.Err().(*websocket.CloseError); !ok || err.Code != websocket.ClosePolicyViolation {
+		t.Errorf("expected a policy violation close frame, got %v", c.Err())
+	}
+}
+
+func TestRateLimitStreamOpen(t *testing.T) {
+	srv, handled := newCountingServer(t)
+	srv.OnStream("upload", uploadHandler)
+	srv.SetRateLimit(ss.RateLimit{Rate: 1, Burst: 2})
+	srv.SetEventRateLimit("upload", ss.RateLimit{Rate: 1, Burst: 1})
+
+	c := srv.Dial()
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up1", Event: "upload"})
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up2", Event: "upload"})
+
+	if abort := expectStreamMsg(t, c, "__ss_stream_abort"); abort.ID != "up2" || abort.Error != "rate limited" {
+		t.Errorf("expected the upload limit to abort up2, got %+v", abort)
+	}
+	c.ExpectNone("__ss_stream_abort", 20*time.Millisecond)
+
+	//both opens took a token from the limit shared by all events
+	c.Emit("shout", "")
+	if counts := count(handled, 50*time.Millisecond); counts["shout"] != 0 {
+		t.Errorf("expected the shout to be dropped, got %v", counts)
+	}
+
+	c.Emit("__ss_stream_end", &streamMsg{ID: "up1"})
+	c.Expect("done")
+}
+
+func TestRateLimitStreamChunks(t *testing.T) {
+	srv, _ := newCountingServer(t)
+	srv.OnStream("upload", uploadHandler)
+	srv.SetRateLimit(ss.RateLimit{Rate: 1, Burst: 1, Policy: ss.RateLimitDisconnect})
+
+	//only the open takes a token, the chunks and the end are not limited
+	c := srv.Dial()
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up1", Event: "upload"})
+	for i := 0; i < 5; i++ {
+		c.Emit("__ss_stream_chunk:up1", []byte("chunk"))
+		expectStreamMsg(t, c, "__ss_stream_ack")
+	}
+	c.Emit("__ss_stream_end", &streamMsg{ID: "up1"})
+	c.Expect("done")
+
+	if c.Closed() {
+		t.Errorf("expected the stream not to be rate limited, got %v", c.Err())
+	}
+}
@@ -0,0 +1,68 @@
+package ss
+
+import (
+	"github.com/gorilla/websocket"
+)
+
+const (
+	//DefaultReadLimit is the default maximum size in bytes of a message received from a client, see SocketServer.SetReadLimit.
+	//It is 0, no limit, so existing servers keep accepting messages of any size.
+	DefaultReadLimit int64 = 0
+
+	//DefaultMaxEventNameLength is the default maximum length in bytes of the name of an event received from a client,
+	//see SocketServer.SetMaxEventNameLength
+	DefaultMaxEventNameLength = 256
+
+	//CauseMessageTooBig means the client sent a message larger than the SocketServer's read limit, or an event
+	//with a name longer than its maximum event name length
+	CauseMessageTooBig DisconnectCause = "message too big"
+)
+
+//SetReadLimit sets the maximum size in bytes of a message received from a client, including the event name
+//and sac-sock header. Sockets that send a larger message receive a close frame with the websocket.CloseMessageTooBig
+//code and are disconnected with the CauseMessageTooBig reason. The limit defaults to DefaultReadLimit, which is no limit,
+//a limit of 0 or less removes it. Servers open to untrusted clients should set a limit, and use SocketServer.OnStream to
+//receive payloads larger than it.
+//
+//SetReadLimit only affects Sockets that connect after it is called.
+func (serv *SocketServer) SetReadLimit(limit int64) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.readLimit = limit
+}
+
+//SetMaxEventNameLength sets the maximum length in bytes of the name of an event received from a client. Sockets that
+//send an event with a longer name are closed the same way as Sockets that exceed the read limit. The maximum defaults
+//to DefaultMaxEventNameLength, a maximum of 0 or less removes it.
+func (serv *SocketServer) SetMaxEventNameLength(max int) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.maxEventNameLength = max
+}
+
+//SetBufferSizes sets the sizes in bytes of the read and write buffers of new websocket connections, in place of the
+//ReadBufferSize and WriteBufferSize of the websocket.Upgrader. Buffer sizes do not limit the size of messages, see
+//SetReadLimit. A size of 0 uses gorilla/websocket's default of 4096 bytes.
+func (serv *SocketServer) SetBufferSizes(readBufferSize, writeBufferSize int) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+
+	u := *serv.upgrader
+	u.ReadBufferSize = readBufferSize
+	u.WriteBufferSize = writeBufferSize
+	serv.upgrader = &u
+}
+
+//checkEventName closes s and returns false if eventName is longer than the maximum event name length
+func (serv *SocketServer) checkEventName(s *Socket, eventName string) bool {
+	serv.l.RLock()
+	max := serv.maxEventNameLength
+	serv.l.RUnlock()
+
+	if max <= 0 || len(eventName) <= max {
+		return true
+	}
+
+	s.disconnect(CauseMessageTooBig, websocket.CloseMessageTooBig, "event name too long")
+	return false
+}
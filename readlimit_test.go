@@ -0,0 +1,50 @@
+package ss_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/sacrificial-socket"
+	"strings"
+	"testing"
+)
+
+func TestReadLimit(t *testing.T) {
+	srv, reasons := newReasonServer(t)
+	srv.SetReadLimit(1024)
+	srv.SetMaxEventNameLength(16)
+	srv.On("echo", func(s *ss.Socket, data []byte) {
+		s.Emit("echo", string(data))
+	})
+
+	c := srv.Dial()
+	c.Emit("echo", strings.Repeat("a", 1000))
+	c.Expect("echo")
+
+	c.Emit("echo", strings.Repeat("a", 2000))
+	c.ExpectClosed()
+	expectReason(t, reasons, ss.CauseMessageTooBig, websocket.CloseMessageTooBig)
+	if err, ok := c.Err().(*websocket.CloseError); !ok || err.Code != websocket.CloseMessageTooBig {
+		t.Errorf("expected a message too big close frame, got %v", c.Err())
+	}
+
+	c = srv.Dial()
+	c.Emit(strings.Repeat("e", 17), "")
+	c.ExpectClosed()
+	expectReason(t, reasons, ss.CauseMessageTooBig, websocket.CloseMessageTooBig)
+	if err, ok := c.Err().(*websocket.CloseError); !ok || err.Text != "event name too long" {
+		t.Errorf("expected an event name too long close frame, got %v", c.Err())
+	}
+}
+
+func TestReadLimitDefault(t *testing.T) {
+	srv, _ := newReasonServer(t)
+	srv.On("echo", func(s *ss.Socket, data []byte) {
+		s.Emit("echo", len(data))
+	})
+
+	//messages of any size are accepted unless a limit is set
+	c := srv.Dial()
+	c.Emit("echo", strings.Repeat("a", 2*1024*1024))
+	if n := c.Expect("echo").String(); n != "2097152" {
+		t.Errorf("expected the whole message to be received, got %s bytes", n)
+	}
+}
@@ -0,0 +1,50 @@
+package ss_test
+
+import (
+	"errors"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoomPolicy(t *testing.T) {
+	errNotAllowed := errors.New("not allowed")
+
+	serv := ss.NewServer()
+	serv.SetRoomPolicy(func(s *ss.Socket, roomName string) error {
+		if strings.HasPrefix(roomName, "public:") {
+			return nil
+		}
+		return errNotAllowed
+	})
+	serv.On("join", func(s *ss.Socket, data []byte) {
+		err := s.Join(string(data))
+		if err != nil {
+			s.Emit("join error", err.Error())
+			return
+		}
+		s.Emit("joined", string(data))
+	})
+
+	srv := sstest.NewServer(t, serv)
+	victim, attacker := srv.Dial(), srv.Dial()
+
+	attacker.Emit("join", "public:lobby")
+	attacker.Expect("joined")
+
+	attacker.Emit("join", "private")
+	if msg := attacker.Expect("join error"); msg.String() != errNotAllowed.Error() {
+		t.Errorf("expected %q, got %q", errNotAllowed, msg)
+	}
+
+	attacker.Emit("join", ss.ReservedRoomPrefix+victim.ID())
+	if msg := attacker.Expect("join error"); msg.String() != ss.ErrReservedRoom.Error() {
+		t.Errorf("expected %q, got %q", ss.ErrReservedRoom, msg)
+	}
+
+	srv.Socketcast(victim.ID(), "secret", "for the victim only")
+	victim.Expect("secret")
+	attacker.ExpectNone("secret", 50*time.Millisecond)
+}
@@ -0,0 +1,125 @@
+package ss
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/raz-varren/log"
+	"github.com/raz-varren/sacrificial-socket/ssproto"
+)
+
+const (
+	//CodeInvalid is the EventError code sent when an event's payload does not pass the
+	//Validator set with SocketServer.SetSchema
+	CodeInvalid = "invalid"
+)
+
+var (
+	ErrBinaryPayload = errors.New("binary payloads can not be validated")
+)
+
+//Validator validates event payloads before they reach an event handler. See package ssschema
+//for a Validator that checks payloads against a JSON Schema.
+type Validator interface {
+	//Validate returns an error describing why v is not a valid payload. v is the payload decoded
+	//the way json.Unmarshal decodes into an interface{}, string payloads are passed as a string.
+	Validate(v interface{}) error
+}
+
+//SetSchema sets the Validator that checks the payload of every event named eventName before it is
+//dispatched to its handler. Events with invalid payloads are not dispatched, and the client receives
+//an ErrorEvent with the CodeInvalid code and the validation error as its message instead.
+//
+//JSON payloads are decoded before they are validated and string payloads are validated as JSON strings.
+//Binary payloads can not be validated and are always rejected. Passing a nil Validator removes the schema.
+//
+//eventName may also be a pattern registered with OnPattern, in which case the Validator checks every event
+//dispatched to that pattern's event function that has no Validator of its own.
+func (serv *SocketServer) SetSchema(eventName string, v Validator) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+
+	if v == nil {
+		delete(serv.schemas, eventName)
+		return
+	}
+	serv.schemas[eventName] = v
+}
+
+//SetValidateOutgoing enables validating the payloads passed to Emit, Roomcast, Broadcast, and Socketcast
+//against the Validator set for their event with SetSchema. Invalid payloads are not sent, and the validation
+//error is returned.
+//
+//Outgoing validation encodes and decodes every payload an extra time, so it is best used during development
+//and testing.
+func (serv *SocketServer) SetValidateOutgoing(enabled bool) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.validateOutgoing = enabled
+}
+
+//validate checks the payload of msg against the Validator of its event, or of pattern if its event has
+//none. pattern is the OnPattern pattern msg was matched with, if any.
+func (serv *SocketServer) validate(msg *Message, pattern string) error {
+	serv.l.RLock()
+	v, exists := serv.schemas[msg.EventName]
+	if !exists && pattern != "" {
+		v, exists = serv.schemas[pattern]
+	}
+	serv.l.RUnlock()
+
+	if !exists {
+		return nil
+	}
+
+	var payload interface{}
+	switch msg.Type {
+	case ssproto.TypeJSON:
+		err := json.Unmarshal(msg.Raw, &payload)
+		if err != nil {
+			return err
+		}
+	case ssproto.TypeStr:
+		payload = string(msg.Raw)
+	default:
+		return ErrBinaryPayload
+	}
+
+	return v.Validate(payload)
+}
+
+//validateOutgoingData checks data against the Validator of eventName when outgoing validation is enabled
+func (serv *SocketServer) validateOutgoingData(eventName string, data interface{}) error {
+	serv.l.RLock()
+	enabled := serv.validateOutgoing
+	v, exists := serv.schemas[eventName]
+	serv.l.RUnlock()
+
+	if !enabled || !exists {
+		return nil
+	}
+
+	var payload interface{}
+	switch d := data.(type) {
+	case string:
+		payload = d
+	case []byte:
+		return ErrBinaryPayload
+	default:
+		jsonData, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(jsonData, &payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	return v.Validate(payload)
+}
+
+//reject tells the client that the payload of msg was rejected by the event's Validator
+func (serv *SocketServer) reject(s *Socket, msg *Message, err error) {
+	log.Debug.Println(s.ID(), "sent an invalid", msg.EventName, "payload:", err)
+	s.EmitError(msg.EventName, CodeInvalid, err.Error())
+}
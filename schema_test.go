@@ -0,0 +1,97 @@
+package ss_test
+
+import (
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/ssschema"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"testing"
+	"time"
+)
+
+func TestSetSchema(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnMessage("chat", func(s *ss.Socket, msg *ss.Message) {
+		s.Emit("chatted", msg.String())
+	})
+	serv.SetSchema("chat", ssschema.MustCompile(`{
+		"type": "object",
+		"properties": {"message": {"type": "string", "maxLength": 5}},
+		"required": ["message"]
+	}`))
+
+	srv := sstest.NewServer(t, serv)
+	c := srv.Dial()
+
+	c.Emit("chat", map[string]string{"message": "hi"})
+	c.Expect("chatted")
+
+	for _, payload := range []interface{}{
+		map[string]string{"message": "too long"},
+		map[string]int{"message": 5},
+		"hi",
+		[]byte("hi"),
+	} {
+		c.Emit("chat", payload)
+
+		var e ss.EventError
+		err := c.Expect(ss.ErrorEvent).Unmarshal(&e)
+		if err != nil || e.Event != "chat" || e.Code != ss.CodeInvalid || e.Message == "" {
+			t.Errorf("%v: expected an invalid error for chat, got %+v (%v)", payload, e, err)
+		}
+		c.ExpectNone("chatted", 20*time.Millisecond)
+	}
+}
+
+func TestSetSchemaPattern(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnPattern("chat:{room}", func(s *ss.Socket, msg *ss.Message) {
+		s.Emit("chatted", msg.Param("room"))
+	})
+	serv.SetSchema("chat:{room}", ssschema.MustCompile(`{"type": "string", "maxLength": 5}`))
+	serv.SetSchema("chat:news", ssschema.MustCompile(`{"type": "string"}`))
+
+	srv := sstest.NewServer(t, serv)
+	c := srv.Dial()
+
+	c.Emit("chat:lobby", "hi")
+	if room := c.Expect("chatted").String(); room != "lobby" {
+		t.Errorf("expected a chat in the lobby, got %s", room)
+	}
+
+	c.Emit("chat:lobby", "too long")
+	var e ss.EventError
+	err := c.Expect(ss.ErrorEvent).Unmarshal(&e)
+	if err != nil || e.Event != "chat:lobby" || e.Code != ss.CodeInvalid {
+		t.Errorf("expected the pattern's schema to reject chat:lobby, got %+v (%v)", e, err)
+	}
+	c.ExpectNone("chatted", 20*time.Millisecond)
+
+	//the exact event name's schema takes precedence over the pattern's
+	c.Emit("chat:news", "too long")
+	if room := c.Expect("chatted").String(); room != "news" {
+		t.Errorf("expected a chat in news, got %s", room)
+	}
+}
+
+func TestValidateOutgoing(t *testing.T) {
+	serv := ss.NewServer()
+	serv.SetSchema("count", ssschema.MustCompile(`{"type": "integer"}`))
+	serv.SetValidateOutgoing(true)
+
+	serv.On("emit", func(s *ss.Socket, data []byte) {
+		if err := s.Emit("count", "one"); err == nil {
+			t.Error("expected Emit to return an error for an invalid payload")
+		}
+		s.Roomcast(ss.ReservedRoomPrefix+s.ID(), "count", 1.5)
+		s.Emit("count", 1)
+	})
+
+	srv := sstest.NewServer(t, serv)
+	c := srv.Dial()
+
+	c.Emit("emit", "")
+	if got := c.Expect("count").String(); got != "1" {
+		t.Errorf("expected only the valid count, got %q", got)
+	}
+	c.ExpectNone("count", 20*time.Millisecond)
+}
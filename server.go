@@ -3,54 +3,98 @@ Package ss (Sacrificial-Socket) is a Go server library and pure JS client librar
 
 Sacrificial-Socket supports rooms, roomcasts, broadcasts, and event emitting just like Socket.IO, but with one key difference. The data passed into event functions is not an interface{} that is implied to be a string or map[string]interface{}, but is always passed in as a []byte making it easier to unmarshal into your own JSON data structs, convert to a string, or keep as binary data without the need to check the data's type before processing it. It also means there aren't any unnecessary conversions to the data between the client and the server.
 
-Sacrificial-Socket also has a MultihomeBackend interface for syncronizing broadcasts and roomcasts across multiple instances of Sacrificial-Socket running on multiple machines. Out of the box Sacrificial-Socket provides a MultihomeBackend interface for the popular noSQL database MongoDB, one for the moderately popular key/value storage engine Redis, and one for the not so popular GRPC protocol, for syncronizing instances on multiple machines.
+Sacrificial-Socket also has a MultihomeBackend interface for syncronizing broadcasts and roomcasts across multiple instances of Sacrificial-Socket running on multiple machines. Out of the box Sacrificial-Socket provides a MultihomeBackend interface for the popular noSQL database MongoDB, one for the moderately popular key/value storage engine Redis, and one for the not so popular GRPC protocol, for syncronizing instances on multiple machines. There is also an in process MultihomeBackend, ssmem, for testing multiple instances without any external services.
 */
 package ss
 
 import (
+	"context"
 	"github.com/gorilla/websocket"
 	"github.com/raz-varren/log"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
-const ( //                        ASCII chars
-	startOfHeaderByte uint8 = 1 //SOH
-	startOfDataByte         = 2 //STX
-
+const (
 	//SubProtocol is the official sacrificial-socket sub protocol
 	SubProtocol string = "sac-sock"
 )
 
 type event struct {
 	eventName    string
-	eventHandler func(*Socket, []byte)
+	eventHandler func(*Socket, *Message)
 }
 
 //SocketServer manages the coordination between
 //sockets, rooms, events and the socket hub
 type SocketServer struct {
-	hub              *socketHub
-	events           map[string]*event
-	onConnectFunc    func(*Socket)
-	onDisconnectFunc func(*Socket)
-	l                *sync.RWMutex
-	upgrader         *websocket.Upgrader
+	rejectedOrigins         uint64 //accessed atomically, keep it 64 bit aligned
+	hub                     *socketHub
+	events                  map[string]*event
+	patterns                []*eventPattern
+	onAnyFuncs              []func(*Socket, *Message)
+	onUnknownFunc           *event
+	onErrorFunc             func(*Socket, string, error)
+	closePanicking          bool
+	ctx                     context.Context
+	cancel                  context.CancelFunc
+	connContext             func(context.Context, *http.Request) context.Context
+	acls                    map[string]*ACL
+	schemas                 map[string]Validator
+	streamEvents            map[string]func(*Socket, io.Reader)
+	onConnectFuncs          []func(*Socket)
+	onDisconnectFuncs       []func(*Socket)
+	onDisconnectReasonFuncs []func(*Socket, *DisconnectReason)
+	l                       *sync.RWMutex
+	upgrader                *websocket.Upgrader
+	originPolicy            OriginPolicy
+	authenticator           Authenticator
+	roomPolicy              func(*Socket, string) error
+	validateOutgoing        bool
+	pingInterval            time.Duration
+	pingTimeout             time.Duration
+	idleTimeout             time.Duration
+	maxAge                  time.Duration
+	maxAgeJitter            time.Duration
+	conns                   *connCounter
+	limits                  connLimits
+	trustedProxies          []*net.IPNet
+	rateLimit               RateLimit
+	eventRateLimits         map[string]*RateLimit
+	fanout                  *fanoutBucket
+	fanoutQuota             FanoutQuota
+	socketFanoutQuota       FanoutQuota
+	readLimit               int64
+	maxEventNameLength      int
+	maxStreams              int
 }
 
 //NewServer creates a new instance of SocketServer
 func NewServer() *SocketServer {
 	s := &SocketServer{
-		hub:      newHub(),
-		events:   make(map[string]*event),
-		l:        &sync.RWMutex{},
-		upgrader: DefaultUpgrader(),
+		hub:                newHub(),
+		events:             make(map[string]*event),
+		acls:               make(map[string]*ACL),
+		eventRateLimits:    make(map[string]*RateLimit),
+		schemas:            make(map[string]Validator),
+		streamEvents:       make(map[string]func(*Socket, io.Reader)),
+		l:                  &sync.RWMutex{},
+		upgrader:           DefaultUpgrader(),
+		conns:              newConnCounter(),
+		fanout:             newFanoutBucket(),
+		readLimit:          DefaultReadLimit,
+		maxEventNameLength: DefaultMaxEventNameLength,
+		maxStreams:         DefaultMaxStreams,
 	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 
 	return s
 }
@@ -77,13 +121,14 @@ func (serv *SocketServer) EnableSignalShutdown(complete chan<- bool) {
 //method on any MultihomeBackend that is currently set.
 func (serv *SocketServer) Shutdown() bool {
 	log.Info.Println("shutting down")
+	serv.cancel()
 	//complete := serv.hub.shutdown()
 
 	serv.hub.shutdownCh <- true
 	socketList := <-serv.hub.socketList
 
 	for _, s := range socketList {
-		s.Close()
+		s.disconnect(CauseShutdown, websocket.CloseGoingAway, "server shutdown")
 	}
 
 	if serv.hub.multihomeEnabled {
@@ -103,14 +148,76 @@ type EventHandler interface {
 }
 
 //On registers event functions to be called on individual Socket connections
-//when the server's socket receives an Emit from the client's socket.
+//when the server's socket receives an Emit from the client's socket. Registering an
+//event function for an eventName that already has one replaces it.
 //
 //Any event functions registered with On, must be safe for concurrent use by multiple
 //go routines
 func (serv *SocketServer) On(eventName string, handleFunc func(*Socket, []byte)) {
+	serv.OnMessage(eventName, func(s *Socket, msg *Message) {
+		handleFunc(s, msg.Raw)
+	})
+}
+
+//OnMessage has the same functionality as On, but the registered event function receives
+//a *Message, which describes whether the client sent a string, binary, or JSON payload.
+//
+//Any event functions registered with OnMessage, must be safe for concurrent use by multiple
+//go routines
+func (serv *SocketServer) OnMessage(eventName string, handleFunc func(*Socket, *Message)) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
 	serv.events[eventName] = &event{eventName, handleFunc} //you think you can handle the func?
 }
 
+//Once registers an event function that is only called for the first eventName event received
+//by the SocketServer, after which it is removed as if Off had been called.
+func (serv *SocketServer) Once(eventName string, handleFunc func(*Socket, []byte)) {
+	e := onceEvent(eventName, handleFunc, func(e *event) {
+		serv.l.Lock()
+		defer serv.l.Unlock()
+		if serv.events[eventName] == e {
+			delete(serv.events, eventName)
+		}
+	})
+
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.events[eventName] = e
+}
+
+//onceEvent returns an event that only calls handleFunc the first time it is dispatched. remove is
+//called just before handleFunc to unregister the event from wherever it was registered.
+func onceEvent(eventName string, handleFunc func(*Socket, []byte), remove func(*event)) *event {
+	e := &event{eventName: eventName}
+	called := int32(0)
+
+	e.eventHandler = func(s *Socket, msg *Message) {
+		if !atomic.CompareAndSwapInt32(&called, 0, 1) {
+			return
+		}
+		remove(e)
+		handleFunc(s, msg.Raw)
+	}
+	return e
+}
+
+//Off removes the event functions registered for eventName with On, OnMessage, OnEvent, Once, OnStream, or
+//OnPattern. Events received for eventName afterwards are ignored, events already being handled are not interrupted.
+func (serv *SocketServer) Off(eventName string) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	delete(serv.events, eventName)
+	delete(serv.streamEvents, eventName)
+
+	for i, p := range serv.patterns {
+		if p.pattern == eventName {
+			serv.patterns = append(serv.patterns[:i:i], serv.patterns[i+1:]...)
+			break
+		}
+	}
+}
+
 //OnEvent has the same functionality as On, but accepts
 //an EventHandler interface instead of a handler function.
 func (serv *SocketServer) OnEvent(h EventHandler) {
@@ -118,15 +225,21 @@ func (serv *SocketServer) OnEvent(h EventHandler) {
 }
 
 //OnConnect registers an event function to be called whenever a new Socket connection
-//is created
+//is created. OnConnect may be called more than once, the event functions are called in
+//the order they were registered.
 func (serv *SocketServer) OnConnect(handleFunc func(*Socket)) {
-	serv.onConnectFunc = handleFunc
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.onConnectFuncs = append(serv.onConnectFuncs, handleFunc)
 }
 
 //OnDisconnect registers an event function to be called as soon as a Socket connection
-//is closed
+//is closed. OnDisconnect may be called more than once, the event functions are called in
+//the order they were registered.
 func (serv *SocketServer) OnDisconnect(handleFunc func(*Socket)) {
-	serv.onDisconnectFunc = handleFunc
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.onDisconnectFuncs = append(serv.onDisconnectFuncs, handleFunc)
 }
 
 //WebHandler returns a http.Handler to be passed into http.Handle
@@ -138,13 +251,54 @@ func (serv *SocketServer) WebHandler() http.Handler {
 
 //ServeHTTP will upgrade a http request to a websocket using the sac-sock subprotocol
 func (serv *SocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ws, err := serv.upgrader.Upgrade(w, r, nil)
+	serv.l.RLock()
+	u := *serv.upgrader
+	policy := serv.originPolicy
+	auth := serv.authenticator
+	limits := serv.limits
+	serv.l.RUnlock()
+
+	if policy == nil {
+		policy = u.CheckOrigin
+	}
+	if policy == nil {
+		policy = SameOrigin
+	}
+
+	if !policy(r) {
+		atomic.AddUint64(&serv.rejectedOrigins, 1)
+		log.Warn.Println("rejected websocket origin:", r.Header.Get("Origin"), "remote addr:", r.RemoteAddr)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	var claims Claims
+	if auth != nil {
+		var err error
+		claims, err = auth.Authenticate(r)
+		if err != nil {
+			log.Warn.Println("websocket authentication failed:", err, "remote addr:", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	ip, identity := serv.ClientIP(r), claims.Subject()
+	if status := serv.conns.acquire(ip, identity, limits); status != 0 {
+		log.Warn.Println("rejected websocket connection over the connection limits, ip:", ip, "identity:", identity)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	defer serv.conns.release(ip, identity)
+
+	u.CheckOrigin = AnyOrigin //the origin has already been checked
+	ws, err := u.Upgrade(w, r, nil)
 	if err != nil {
 		log.Err.Println(err)
 		return
 	}
 
-	serv.loop(ws)
+	serv.loop(ws, r, claims)
 }
 
 //DefaultUpgrader returns a websocket upgrader suitable for creating sacrificial-socket websockets.
@@ -156,9 +310,39 @@ func DefaultUpgrader() *websocket.Upgrader {
 	return u
 }
 
-//SetUpgrader sets the websocket.Upgrader used by the SocketServer.
+//SetUpgrader sets the websocket.Upgrader used by the SocketServer. A copy of u is used, with the
+//sac-sock sub protocol added to its Subprotocols if it is missing, so changes made to u after calling
+//SetUpgrader have no effect.
+//
+//If u has a CheckOrigin function it is only used when no OriginPolicy has been set with SocketServer.SetOriginPolicy.
 func (serv *SocketServer) SetUpgrader(u *websocket.Upgrader) {
-	serv.upgrader = u
+	up := *u
+	up.Subprotocols = append([]string{}, u.Subprotocols...)
+
+	hasSubProtocol := false
+	for _, p := range up.Subprotocols {
+		if p == SubProtocol {
+			hasSubProtocol = true
+			break
+		}
+	}
+	if !hasSubProtocol {
+		up.Subprotocols = append(up.Subprotocols, SubProtocol)
+	}
+
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.upgrader = &up
+}
+
+//SetRoomPolicy sets a function that is called every time a Socket tries to join a room with Socket.Join.
+//If policy returns an error the Socket does not join the room, and Socket.Join returns the error.
+//
+//Rooms starting with ReservedRoomPrefix can never be joined, regardless of the policy.
+func (serv *SocketServer) SetRoomPolicy(policy func(s *Socket, roomName string) error) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.roomPolicy = policy
 }
 
 //SetMultihomeBackend registers a MultihomeBackend interface and calls it's Init() method
@@ -166,71 +350,174 @@ func (serv *SocketServer) SetMultihomeBackend(b MultihomeBackend) {
 	serv.hub.setMultihomeBackend(b)
 }
 
-//Roomcast dispatches an event to all Sockets in the specified room.
-func (serv *SocketServer) Roomcast(roomName, eventName string, data interface{}) {
-	serv.hub.roomcast(&RoomMsg{roomName, eventName, data})
+//Roomcast dispatches an event to all Sockets in the specified room. An error is returned if the
+//event is rejected by the SocketServer's FanoutQuota or outgoing validation.
+func (serv *SocketServer) Roomcast(roomName, eventName string, data interface{}) error {
+	return serv.cast(nil, roomName, false, eventName, data)
+}
+
+//Broadcast dispatches an event to all Sockets on the SocketServer. An error is returned if the
+//event is rejected by the SocketServer's FanoutQuota or outgoing validation.
+func (serv *SocketServer) Broadcast(eventName string, data interface{}) error {
+	return serv.cast(nil, "", true, eventName, data)
+}
+
+//Kick disconnects the Socket with the specified ID, even if it is connected to another
+//SocketServer sharing this SocketServer's MultihomeBackend.
+func (serv *SocketServer) Kick(socketID string) {
+	serv.hub.control(&ControlMsg{Action: ControlDisconnect, SocketID: socketID})
 }
 
-//Broadcast dispatches an event to all Sockets on the SocketServer.
-func (serv *SocketServer) Broadcast(eventName string, data interface{}) {
-	serv.hub.broadcast(&BroadcastMsg{eventName, data})
+//SocketJoin adds the Socket with the specified ID to roomName, even if it is connected to another
+//SocketServer sharing this SocketServer's MultihomeBackend. The join is checked the same way as
+//Socket.Join by the SocketServer the Socket is connected to, and is logged and dropped if it is not allowed.
+func (serv *SocketServer) SocketJoin(socketID, roomName string) {
+	serv.hub.control(&ControlMsg{Action: ControlJoin, SocketID: socketID, RoomName: roomName})
+}
+
+//SocketLeave removes the Socket with the specified ID from roomName, even if it is connected to another
+//SocketServer sharing this SocketServer's MultihomeBackend.
+func (serv *SocketServer) SocketLeave(socketID, roomName string) {
+	serv.hub.control(&ControlMsg{Action: ControlLeave, SocketID: socketID, RoomName: roomName})
+}
+
+//GetSocket returns the Socket with the specified ID if it is connected to this SocketServer.
+//Sockets connected to other SocketServers through a MultihomeBackend will not be found.
+func (serv *SocketServer) GetSocket(socketID string) (*Socket, bool) {
+	s := serv.hub.getSocket(socketID)
+	return s, s != nil
+}
+
+//GetSockets returns a list of all Sockets connected to this SocketServer
+func (serv *SocketServer) GetSockets() []*Socket {
+	return serv.hub.listSockets()
+}
+
+//GetRooms returns every room on this SocketServer mapped to the IDs of its member Sockets.
+//This includes each Socket's private ReservedRoomPrefix room.
+func (serv *SocketServer) GetRooms() map[string][]string {
+	return serv.hub.listRooms()
 }
 
 //Socketcast dispatches an event to the specified socket ID.
-func (serv *SocketServer) Socketcast(socketID, eventName string, data interface{}) {
-	serv.Roomcast("__socket_id:"+socketID, eventName, data)
+func (serv *SocketServer) Socketcast(socketID, eventName string, data interface{}) error {
+	return serv.Roomcast(ReservedRoomPrefix+socketID, eventName, data)
 }
 
 //loop handles all the coordination between new sockets
 //reading frames and dispatching events
-func (serv *SocketServer) loop(ws *websocket.Conn) {
-	s := newSocket(serv, ws)
+func (serv *SocketServer) loop(ws *websocket.Conn, r *http.Request, claims Claims) {
+	s := newSocket(serv, ws, r, claims)
 	log.Debug.Println(s.ID(), "connected")
 
 	defer s.Close()
 
-	s.Join("__socket_id:"+s.ID())
+	s.join(ReservedRoomPrefix + s.ID())
 
 	serv.l.RLock()
-	e := serv.onConnectFunc
+	onConnect := serv.onConnectFuncs
 	serv.l.RUnlock()
 
-	if e != nil {
-		e(s)
+	for _, e := range onConnect {
+		serv.call(s, ConnectEvent, func() { e(s) })
+	}
+
+	serv.l.RLock()
+	pingInterval, pingTimeout := serv.pingInterval, serv.pingTimeout
+	readLimit := serv.readLimit
+	serv.l.RUnlock()
+
+	if readLimit > 0 {
+		ws.SetReadLimit(readLimit)
+	}
+
+	if pingInterval > 0 {
+		s.keepalive(pingInterval, pingTimeout)
+	}
+
+	if idleTimeout, maxAge := serv.lifetimeLimits(); idleTimeout > 0 || maxAge > 0 {
+		go s.watchLifetime(idleTimeout, maxAge)
 	}
 
+	limiter := newRateLimiter()
+
 	for {
-		msg, err := s.receive()
-		if ignorableError(err) {
-			return
+		//the deadline is set right before reading, so time spent delayed by a RateLimit is not held against the client
+		if pingInterval > 0 {
+			ws.SetReadDeadline(time.Now().Add(pingInterval + pingTimeout))
 		}
+
+		msgType, frame, err := s.receive()
 		if err != nil {
-			log.Err.Println(err)
+			reason := readReason(err)
+			if reason.Cause == CauseReadError && !ignorableError(err) {
+				log.Err.Println(s.ID(), err)
+			}
+			s.close(reason)
 			return
 		}
 
-		eventName := ""
-		contentIdx := 0
+		s.touch()
+
+		msg, err := newMessage(msgType, frame)
+		if err != nil {
+			log.Warn.Println(s.ID(), "bad frame:", err)
+			continue
+		}
+
+		if !serv.checkEventName(s, msg.EventName) {
+			return
+		}
 
-		for idx, chr := range msg {
-			if chr == startOfDataByte {
-				eventName = string(msg[:idx])
-				contentIdx = idx + 1
-				break
+		if strings.HasPrefix(msg.EventName, streamPrefix) {
+			if msg.EventName == streamOpenEvent && !serv.limitStreamOpen(s, limiter, msg) {
+				continue
 			}
+			serv.handleStreamMsg(s, msg)
+			continue
 		}
-		if eventName == "" {
-			log.Warn.Println("no event to dispatch")
+
+		if !serv.limit(s, limiter, msg) {
 			continue
 		}
 
+		e, pattern, exists := serv.lookup(s, msg)
+
 		serv.l.RLock()
-		e, exists := serv.events[eventName]
+		onAny := serv.onAnyFuncs
+		if !exists {
+			e, exists = serv.onUnknownFunc, serv.onUnknownFunc != nil
+		}
 		serv.l.RUnlock()
 
-		if exists {
-			go e.eventHandler(s, msg[contentIdx:])
+		if !exists && len(onAny) == 0 {
+			continue
+		}
+
+		if !serv.allowed(s, msg.EventName, pattern) {
+			serv.deny(s, msg.EventName)
+			continue
+		}
+
+		if err := serv.validate(msg, pattern); err != nil {
+			serv.reject(s, msg, err)
+			continue
 		}
+
+		go dispatch(s, msg, e, onAny)
+	}
+}
+
+//dispatch calls the OnAny event functions and then e, which may be nil, with msg
+func dispatch(s *Socket, msg *Message, e *event, onAny []func(*Socket, *Message)) {
+	defer s.serv.recoverPanic(s, msg.EventName)
+
+	for _, f := range onAny {
+		f(s, msg)
+	}
+
+	if e != nil {
+		e.eventHandler(s, msg)
 	}
 }
 
@@ -2,10 +2,15 @@ package ss_test
 
 import (
 	"encoding/json"
+	"github.com/gorilla/websocket"
 	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/ssproto"
+	"github.com/raz-varren/sacrificial-socket/sstest"
 	"log"
 	"net/http"
 	"os"
+	"testing"
+	"time"
 )
 
 func ExampleNewServer() {
@@ -24,7 +29,7 @@ func ExampleNewServer() {
 		os.Exit(0)
 	}()
 
-	http.Handle("/socket", s)
+	http.Handle("/socket", serv)
 	log.Fatalln(http.ListenAndServe(":8080", nil))
 }
 
@@ -53,7 +58,11 @@ func Join(s *ss.Socket, data []byte) {
 	err := json.Unmarshal(data, &j)
 	check(err)
 
-	s.Join(j.Room)
+	err = s.Join(j.Room)
+	if err != nil {
+		s.Emit("echo", "could not join: "+j.Room)
+		return
+	}
 	s.Emit("echo", "joined: "+j.Room)
 }
 
@@ -87,3 +96,63 @@ func check(err error) {
 		log.Fatalln(err)
 	}
 }
+
+//legacyFrame builds a frame without a data type header, as sent by older clients
+func legacyFrame(eventName string, payload []byte) []byte {
+	return append(append([]byte(eventName), ssproto.StartOfDataByte), payload...)
+}
+
+func TestMessageType(t *testing.T) {
+	msgs := make(chan *ss.Message, 1)
+	serv := ss.NewServer()
+	serv.OnMessage("type", func(s *ss.Socket, msg *ss.Message) {
+		msgs <- msg
+	})
+	srv := sstest.NewServer(t, serv)
+
+	d := &websocket.Dialer{Subprotocols: []string{ss.SubProtocol}}
+	ws, _, err := d.Dial(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	encode := func(data interface{}) []byte {
+		frame, _, err := ssproto.Encode("type", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return frame
+	}
+
+	tests := []struct {
+		name    string
+		msgType int
+		frame   []byte
+		want    ssproto.DataType
+		raw     string
+	}{
+		{"string", websocket.TextMessage, encode("hello"), ssproto.TypeStr, "hello"},
+		{"binary", websocket.BinaryMessage, encode([]byte{0, 1, 2}), ssproto.TypeBin, "\x00\x01\x02"},
+		{"json", websocket.TextMessage, encode(map[string]int{"a": 1}), ssproto.TypeJSON, `{"a":1}`},
+		{"header wins over message type", websocket.BinaryMessage, encode("hello"), ssproto.TypeStr, "hello"},
+		{"legacy text", websocket.TextMessage, legacyFrame("type", []byte("hello")), ssproto.TypeStr, "hello"},
+		{"legacy binary", websocket.BinaryMessage, legacyFrame("type", []byte{0, 1, 2}), ssproto.TypeBin, "\x00\x01\x02"},
+	}
+
+	for _, test := range tests {
+		err := ws.WriteMessage(test.msgType, test.frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case msg := <-msgs:
+			if msg.Type != test.want || string(msg.Raw) != test.raw {
+				t.Errorf("%s: expected a %v payload of %q, got a %v payload of %q", test.name, test.want, test.raw, msg.Type, msg.Raw)
+			}
+		case <-time.After(sstest.DefaultTimeout):
+			t.Fatalf("%s: the event was not handled", test.name)
+		}
+	}
+}
@@ -1,47 +1,75 @@
 package ss
 
 import (
-	"bytes"
+	"context"
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"github.com/gorilla/websocket"
 	"github.com/raz-varren/log"
+	"github.com/raz-varren/sacrificial-socket/ssproto"
+	"net"
+	"net/http"
+	"strings"
 	"sync"
 )
 
 var (
 	socketRNG = NewRNG()
+
+	ErrEmptyRoomName = errors.New("room name is empty")
+	ErrReservedRoom  = errors.New("room name uses the reserved " + ReservedRoomPrefix + " prefix")
 )
 
 //Socket represents a websocket connection
 type Socket struct {
-	l      *sync.RWMutex
-	id     string
-	ws     *websocket.Conn
-	closed bool
-	serv   *SocketServer
-	roomsl *sync.RWMutex
-	rooms  map[string]bool
+	lastRead int64 //accessed atomically, keep it 64 bit aligned
+	l        *sync.RWMutex
+	id       string
+	ws       *websocket.Conn
+	closed   bool
+	serv     *SocketServer
+	roomsl   *sync.RWMutex
+	rooms    map[string]bool
+	streams  *streamSet
+	done     chan struct{}
+	attrsl   *sync.RWMutex
+	attrs    map[string]interface{}
+	claims   Claims
+	reason   *DisconnectReason
+	eventsl  *sync.RWMutex
+	events   map[string]*event
+	ctx      context.Context
+	cancel   context.CancelFunc
+	fanout   *fanoutBucket
 }
 
 const (
 	idLen int = 24
 
-	typeJSON string = "J"
-	typeBin         = "B"
-	typeStr         = "S"
+	//ReservedRoomPrefix is the prefix of each Socket's private room, which is used to deliver
+	//Socketcasts. Rooms starting with ReservedRoomPrefix can not be joined with Socket.Join.
+	ReservedRoomPrefix = "__socket_id:"
 )
 
-func newSocket(serv *SocketServer, ws *websocket.Conn) *Socket {
+func newSocket(serv *SocketServer, ws *websocket.Conn, r *http.Request, claims Claims) *Socket {
 	s := &Socket{
-		l:      &sync.RWMutex{},
-		id:     newSocketID(),
-		ws:     ws,
-		closed: false,
-		serv:   serv,
-		roomsl: &sync.RWMutex{},
-		rooms:  make(map[string]bool),
+		l:       &sync.RWMutex{},
+		id:      newSocketID(),
+		ws:      ws,
+		closed:  false,
+		serv:    serv,
+		roomsl:  &sync.RWMutex{},
+		rooms:   make(map[string]bool),
+		streams: newStreamSet(),
+		done:    make(chan struct{}),
+		attrsl:  &sync.RWMutex{},
+		attrs:   make(map[string]interface{}),
+		claims:  claims,
+		eventsl: &sync.RWMutex{},
+		events:  make(map[string]*event),
+		fanout:  newFanoutBucket(),
 	}
+	s.ctx, s.cancel = serv.socketContext(s, r)
 	serv.hub.addSocket(s)
 	return s
 }
@@ -52,15 +80,21 @@ func newSocketID() string {
 	return base64.StdEncoding.EncodeToString(idBuf)
 }
 
-func (s *Socket) receive() ([]byte, error) {
-	_, data, err := s.ws.ReadMessage()
-	return data, err
+func (s *Socket) receive() (int, []byte, error) {
+	return s.ws.ReadMessage()
 }
 
 func (s *Socket) send(msgType int, data []byte) error {
 	s.l.Lock()
-	defer s.l.Unlock()
-	return s.ws.WriteMessage(msgType, data)
+	closed := s.closed
+	err := s.ws.WriteMessage(msgType, data)
+	s.l.Unlock()
+
+	if err != nil && !closed {
+		//send is called from the hub, which close needs to leave rooms
+		go s.close(&DisconnectReason{Cause: CauseWriteError, Code: websocket.CloseAbnormalClosure, Err: err})
+	}
+	return err
 }
 
 //InRoom returns true if s is currently a member of roomName
@@ -83,9 +117,128 @@ func (s *Socket) GetRooms() []string {
 	return roomList
 }
 
+//SetAttr stores an arbitrary value on s under key, replacing any value
+//already stored under that key
+func (s *Socket) SetAttr(key string, value interface{}) {
+	s.attrsl.Lock()
+	defer s.attrsl.Unlock()
+	s.attrs[key] = value
+}
+
+//GetAttr returns the value stored on s under key, and whether or not it exists
+func (s *Socket) GetAttr(key string) (interface{}, bool) {
+	s.attrsl.RLock()
+	defer s.attrsl.RUnlock()
+	value, exists := s.attrs[key]
+	return value, exists
+}
+
+//DelAttr removes the value stored on s under key
+func (s *Socket) DelAttr(key string) {
+	s.attrsl.Lock()
+	defer s.attrsl.Unlock()
+	delete(s.attrs, key)
+}
+
+//On registers an event function that is only called for eventName events emitted by s. Event functions
+//registered on a Socket take precedence over the SocketServer's event function for the same eventName,
+//and are removed when s is closed. The SocketServer's ACLs and schemas still apply.
+func (s *Socket) On(eventName string, handleFunc func(*Socket, []byte)) {
+	s.OnMessage(eventName, func(s *Socket, msg *Message) {
+		handleFunc(s, msg.Raw)
+	})
+}
+
+//OnMessage has the same functionality as Socket.On, but the registered event function receives a *Message
+func (s *Socket) OnMessage(eventName string, handleFunc func(*Socket, *Message)) {
+	s.eventsl.Lock()
+	defer s.eventsl.Unlock()
+
+	if s.events == nil {
+		return //s is closed
+	}
+	s.events[eventName] = &event{eventName, handleFunc}
+}
+
+//Once registers an event function on s that is only called for the next eventName event emitted by s,
+//after which it is removed as if Socket.Off had been called
+func (s *Socket) Once(eventName string, handleFunc func(*Socket, []byte)) {
+	e := onceEvent(eventName, handleFunc, func(e *event) {
+		s.eventsl.Lock()
+		defer s.eventsl.Unlock()
+		if s.events[eventName] == e {
+			delete(s.events, eventName)
+		}
+	})
+
+	s.eventsl.Lock()
+	defer s.eventsl.Unlock()
+
+	if s.events == nil {
+		return
+	}
+	s.events[eventName] = e
+}
+
+//Off removes the event function registered on s for eventName, the SocketServer's event function
+//for eventName, if any, is used again
+func (s *Socket) Off(eventName string) {
+	s.eventsl.Lock()
+	defer s.eventsl.Unlock()
+	delete(s.events, eventName)
+}
+
+//getEvent returns the event function registered on s for eventName
+func (s *Socket) getEvent(eventName string) (*event, bool) {
+	s.eventsl.RLock()
+	defer s.eventsl.RUnlock()
+	e, exists := s.events[eventName]
+	return e, exists
+}
+
+//GetAttrs returns a copy of all the values stored on s
+func (s *Socket) GetAttrs() map[string]interface{} {
+	s.attrsl.RLock()
+	defer s.attrsl.RUnlock()
+
+	attrs := make(map[string]interface{}, len(s.attrs))
+	for key, value := range s.attrs {
+		attrs[key] = value
+	}
+	return attrs
+}
+
 //Join adds s to the specified room. If the room does
-//not exist, it will be created
-func (s *Socket) Join(roomName string) {
+//not exist, it will be created.
+//
+//Rooms starting with ReservedRoomPrefix can not be joined, and if the SocketServer has a
+//room policy it must allow s to join roomName. See SocketServer.SetRoomPolicy.
+func (s *Socket) Join(roomName string) error {
+	if roomName == "" {
+		return ErrEmptyRoomName
+	}
+
+	if strings.HasPrefix(roomName, ReservedRoomPrefix) {
+		return ErrReservedRoom
+	}
+
+	s.serv.l.RLock()
+	policy := s.serv.roomPolicy
+	s.serv.l.RUnlock()
+
+	if policy != nil {
+		err := policy(s, roomName)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.join(roomName)
+	return nil
+}
+
+//join adds s to roomName without any checks
+func (s *Socket) join(roomName string) {
 	s.roomsl.Lock()
 	defer s.roomsl.Unlock()
 	s.serv.hub.joinRoom(&joinRequest{roomName, s})
@@ -102,24 +255,51 @@ func (s *Socket) Leave(roomName string) {
 	delete(s.rooms, roomName)
 }
 
-//Roomcast dispatches an event to all Sockets in the specified room.
-func (s *Socket) Roomcast(roomName, eventName string, data interface{}) {
-	s.serv.hub.roomcast(&RoomMsg{roomName, eventName, data})
+//control performs the action described by c on s
+func (s *Socket) control(c *ControlMsg) {
+	switch c.Action {
+	case ControlDisconnect:
+		s.disconnect(CauseKicked, websocket.ClosePolicyViolation, "kicked")
+	case ControlJoin:
+		err := s.Join(c.RoomName)
+		if err != nil {
+			log.Warn.Println(s.ID(), "could not join", c.RoomName+":", err)
+		}
+	case ControlLeave:
+		s.Leave(c.RoomName)
+	default:
+		log.Warn.Println(s.ID(), "unknown control action:", c.Action)
+	}
+}
+
+//Roomcast dispatches an event to all Sockets in the specified room. An error is returned if the event
+//is rejected by the SocketServer's FanoutQuotas or outgoing validation.
+func (s *Socket) Roomcast(roomName, eventName string, data interface{}) error {
+	return s.serv.cast(s, roomName, false, eventName, data)
 }
 
-//Broadcast dispatches an event to all Sockets on the SocketServer.
-func (s *Socket) Broadcast(eventName string, data interface{}) {
-	s.serv.hub.broadcast(&BroadcastMsg{eventName, data})
+//Broadcast dispatches an event to all Sockets on the SocketServer. An error is returned if the event
+//is rejected by the SocketServer's FanoutQuotas or outgoing validation.
+func (s *Socket) Broadcast(eventName string, data interface{}) error {
+	return s.serv.cast(s, "", true, eventName, data)
 }
 
 //Socketcast dispatches an event to the specified socket ID.
-func (s *Socket) Socketcast(socketID, eventName string, data interface{}) {
-	s.serv.Roomcast("__socket_id:"+socketID, eventName, data)
+func (s *Socket) Socketcast(socketID, eventName string, data interface{}) error {
+	return s.serv.cast(s, ReservedRoomPrefix+socketID, false, eventName, data)
 }
 
 //Emit dispatches an event to s.
 func (s *Socket) Emit(eventName string, data interface{}) error {
-	d, msgType := emitData(eventName, data)
+	err := s.serv.validateOutgoingData(eventName, data)
+	if err != nil {
+		return err
+	}
+
+	d, msgType, err := emitData(eventName, data)
+	if err != nil {
+		return err
+	}
 	return s.send(msgType, d)
 }
 
@@ -128,54 +308,55 @@ func (s *Socket) ID() string {
 	return s.id
 }
 
+//RemoteAddr returns the network address of the client. Behind a reverse proxy this is the address of the proxy.
+func (s *Socket) RemoteAddr() net.Addr {
+	return s.ws.RemoteAddr()
+}
+
 //emitData combines the eventName and data into a payload that is understood
-//by the sac-sock protocol.
-func emitData(eventName string, data interface{}) ([]byte, int) {
-	buf := bytes.NewBuffer(nil)
-	buf.WriteString(eventName)
-	buf.WriteByte(startOfHeaderByte)
-
-	switch d := data.(type) {
-	case string:
-		buf.WriteString(typeStr)
-		buf.WriteByte(startOfDataByte)
-		buf.WriteString(d)
-		return buf.Bytes(), websocket.TextMessage
-
-	case []byte:
-		buf.WriteString(typeBin)
-		buf.WriteByte(startOfDataByte)
-		buf.Write(d)
-		return buf.Bytes(), websocket.BinaryMessage
+//by the sac-sock protocol, and returns the websocket message type it should be sent as.
+func emitData(eventName string, data interface{}) ([]byte, int, error) {
+	d, dataType, err := ssproto.Encode(eventName, data)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	default:
-		buf.WriteString(typeJSON)
-		buf.WriteByte(startOfDataByte)
-		jsonData, err := json.Marshal(d)
-		if err != nil {
-			log.Err.Println(err)
-		} else {
-			buf.Write(jsonData)
-		}
-		return buf.Bytes(), websocket.TextMessage
+	if dataType == ssproto.TypeBin {
+		return d, websocket.BinaryMessage, nil
 	}
+	return d, websocket.TextMessage, nil
 }
 
-//Close closes the Socket connection and removes the Socket
-//from any rooms that it was a member of
+//Close sends the client a normal closure close frame, closes the Socket connection, and removes
+//the Socket from any rooms that it was a member of
 func (s *Socket) Close() {
+	s.disconnect(CauseServerClose, websocket.CloseNormalClosure, "")
+}
+
+//close closes the Socket connection for reason, only the first reason a Socket is closed for is kept
+func (s *Socket) close(reason *DisconnectReason) {
 	s.l.Lock()
 	isAlreadyClosed := s.closed
 	s.closed = true
+	if !isAlreadyClosed {
+		s.reason = reason
+	}
 	s.l.Unlock()
 
 	if isAlreadyClosed { //can't reclose the socket
 		return
 	}
 
-	defer log.Debug.Println(s.ID(), "disconnected")
+	defer log.Debug.Println(s.ID(), "disconnected:", reason)
 
 	s.ws.Close()
+	close(s.done)
+	s.cancel()
+
+	s.eventsl.Lock()
+	s.events = nil
+	s.eventsl.Unlock()
+	s.streams.closeAll(ErrSocketClosed)
 
 	rooms := s.GetRooms()
 
@@ -184,11 +365,16 @@ func (s *Socket) Close() {
 	}
 
 	s.serv.l.RLock()
-	event := s.serv.onDisconnectFunc
+	onDisconnect := s.serv.onDisconnectFuncs
+	onDisconnectReason := s.serv.onDisconnectReasonFuncs
 	s.serv.l.RUnlock()
 
-	if event != nil {
-		event(s)
+	for _, e := range onDisconnect {
+		s.serv.call(s, DisconnectEvent, func() { e(s) })
+	}
+
+	for _, e := range onDisconnectReason {
+		s.serv.call(s, DisconnectEvent, func() { e(s, reason) })
 	}
 
 	s.serv.hub.removeSocket(s)
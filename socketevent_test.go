@@ -0,0 +1,61 @@
+package ss_test
+
+import (
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"testing"
+	"time"
+)
+
+func TestSocketOn(t *testing.T) {
+	serv := ss.NewServer()
+	serv.On("login", func(s *ss.Socket, data []byte) {
+		s.Once("password", func(s *ss.Socket, data []byte) {
+			if string(data) != "hunter2" {
+				s.Emit("step", "denied")
+				return
+			}
+			s.On("whoami", func(s *ss.Socket, data []byte) {
+				s.Emit("me", "alice")
+			})
+			s.Emit("step", "welcome")
+		})
+		s.Emit("step", "password")
+	})
+	serv.On("whoami", func(s *ss.Socket, data []byte) {
+		s.Emit("me", "anonymous")
+	})
+
+	srv := sstest.NewServer(t, serv)
+	a, b := srv.Dial(), srv.Dial()
+
+	a.Emit("password", "hunter2")
+	a.ExpectNone("step", 20*time.Millisecond)
+
+	a.Emit("login", "alice")
+	if step := a.Expect("step").String(); step != "password" {
+		t.Fatalf("expected the password step, got %s", step)
+	}
+	a.Emit("password", "hunter2")
+	if step := a.Expect("step").String(); step != "welcome" {
+		t.Fatalf("expected the welcome step, got %s", step)
+	}
+	a.Emit("password", "hunter2")
+	a.ExpectNone("step", 20*time.Millisecond)
+
+	a.Emit("whoami", "")
+	if me := a.Expect("me").String(); me != "alice" {
+		t.Errorf("expected the socket handler to take precedence, got %s", me)
+	}
+	b.Emit("whoami", "")
+	if me := b.Expect("me").String(); me != "anonymous" {
+		t.Errorf("expected the server handler for other sockets, got %s", me)
+	}
+
+	s, _ := srv.GetSocket(a.ID())
+	s.Off("whoami")
+	a.Emit("whoami", "")
+	if me := a.Expect("me").String(); me != "anonymous" {
+		t.Errorf("expected the server handler after Off, got %s", me)
+	}
+}
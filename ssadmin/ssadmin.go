@@ -0,0 +1,319 @@
+/*
+Package ssadmin provides an http.Handler with a small JSON API for inspecting and managing the sockets and rooms of a running ss.SocketServer.
+
+The handler performs no authentication of its own, so it should only ever be served on an internal address or behind
+whatever authentication your admin tooling already uses:
+
+	serv := ss.NewServer()
+	http.Handle("/socket", serv)
+	http.Handle("/admin/", http.StripPrefix("/admin", ssadmin.NewHandler(serv)))
+
+The following endpoints are provided:
+
+	GET  /sockets     list the sockets connected to this server, along with their rooms and attributes
+	GET  /rooms       list the rooms on this server, along with the IDs of their member sockets
+	POST /disconnect  {"socketId": "..."}
+	POST /join        {"socketId": "...", "room": "..."}
+	POST /leave       {"socketId": "...", "room": "..."}
+	POST /emit        {"socketId": "...", "event": "...", "data": ...}
+	POST /roomcast    {"room": "...", "event": "...", "data": ...}
+	POST /broadcast   {"event": "...", "data": ...}
+
+Every POST endpoint is dispatched through the ss.SocketServer, so it reaches sockets on other servers when a
+ss.MultihomeBackend is in use. disconnect, join, and leave respond with 200 OK when the socket is connected to this server,
+and 202 Accepted when the request was handed off to the ss.MultihomeBackend.
+
+By default a JSON string in data is sent as a string and any other JSON value is sent as JSON.
+Set "type" to "B" and provide base64 encoded data to send binary data instead.
+
+Socket attributes are listed by GET /sockets as they are, so they must be values encoding/json can encode.
+If any of them is not, GET /sockets responds with 500 Internal Server Error.
+*/
+package ssadmin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/raz-varren/log"
+	ss "github.com/raz-varren/sacrificial-socket"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrMissingField   = errors.New("request is missing a required field")
+	ErrBadDataType    = errors.New("type must be one of S, B, or J")
+	ErrEncodeResponse = errors.New("response could not be encoded as JSON")
+)
+
+//SocketInfo describes a socket connected to the ss.SocketServer
+type SocketInfo struct {
+	ID         string                 `json:"id"`
+	Rooms      []string               `json:"rooms"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+//RoomInfo describes a room on the ss.SocketServer
+type RoomInfo struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+//Request is the JSON body accepted by the POST endpoints. Each endpoint only
+//uses the fields that apply to it.
+type Request struct {
+	SocketID string          `json:"socketId"`
+	Room     string          `json:"room"`
+	Event    string          `json:"event"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+}
+
+type handler struct {
+	serv *ss.SocketServer
+	mux  *http.ServeMux
+}
+
+//NewHandler returns an http.Handler serving the admin API for serv
+func NewHandler(serv *ss.SocketServer) http.Handler {
+	h := &handler{serv: serv, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("/sockets", h.get(h.sockets))
+	h.mux.HandleFunc("/rooms", h.get(h.rooms))
+	h.mux.HandleFunc("/disconnect", h.post(h.disconnect))
+	h.mux.HandleFunc("/join", h.post(h.join))
+	h.mux.HandleFunc("/leave", h.post(h.leave))
+	h.mux.HandleFunc("/emit", h.post(h.emit))
+	h.mux.HandleFunc("/roomcast", h.post(h.roomcast))
+	h.mux.HandleFunc("/broadcast", h.post(h.broadcast))
+
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *handler) get(f func() (interface{}, int, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, errResp(errors.New("method not allowed")))
+			return
+		}
+
+		res, status, err := f()
+		if err != nil {
+			writeJSON(w, status, errResp(err))
+			return
+		}
+		writeJSON(w, status, res)
+	}
+}
+
+func (h *handler) post(f func(*Request) (int, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errResp(errors.New("method not allowed")))
+			return
+		}
+
+		var req Request
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errResp(err))
+			return
+		}
+
+		status, err := f(&req)
+		if err != nil {
+			writeJSON(w, status, errResp(err))
+			return
+		}
+		writeJSON(w, status, map[string]bool{"ok": true})
+	}
+}
+
+func (h *handler) sockets() (interface{}, int, error) {
+	sockets := h.serv.GetSockets()
+
+	infos := make([]SocketInfo, 0, len(sockets))
+	for _, s := range sockets {
+		rooms := s.GetRooms()
+		sort.Strings(rooms)
+		infos = append(infos, SocketInfo{
+			ID:         s.ID(),
+			Rooms:      rooms,
+			Attributes: s.GetAttrs(),
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos, http.StatusOK, nil
+}
+
+func (h *handler) rooms() (interface{}, int, error) {
+	rooms := h.serv.GetRooms()
+
+	infos := make([]RoomInfo, 0, len(rooms))
+	for name, members := range rooms {
+		sort.Strings(members)
+		infos = append(infos, RoomInfo{Name: name, Members: members})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, http.StatusOK, nil
+}
+
+func (h *handler) disconnect(req *Request) (int, error) {
+	if req.SocketID == "" {
+		return http.StatusBadRequest, ErrMissingField
+	}
+
+	log.Info.Println("admin: disconnecting socket", req.SocketID)
+	status := h.localStatus(req.SocketID)
+	h.serv.Kick(req.SocketID)
+	return status, nil
+}
+
+func (h *handler) join(req *Request) (int, error) {
+	if req.SocketID == "" || req.Room == "" {
+		return http.StatusBadRequest, ErrMissingField
+	}
+
+	if strings.HasPrefix(req.Room, ss.ReservedRoomPrefix) {
+		return http.StatusBadRequest, ss.ErrReservedRoom
+	}
+
+	status := h.localStatus(req.SocketID)
+	h.serv.SocketJoin(req.SocketID, req.Room)
+	return status, nil
+}
+
+func (h *handler) leave(req *Request) (int, error) {
+	if req.SocketID == "" || req.Room == "" {
+		return http.StatusBadRequest, ErrMissingField
+	}
+
+	status := h.localStatus(req.SocketID)
+	h.serv.SocketLeave(req.SocketID, req.Room)
+	return status, nil
+}
+
+//localStatus returns 200 OK if socketID is connected to this server, otherwise 202 Accepted
+func (h *handler) localStatus(socketID string) int {
+	if _, exists := h.serv.GetSocket(socketID); exists {
+		return http.StatusOK
+	}
+	return http.StatusAccepted
+}
+
+func (h *handler) emit(req *Request) (int, error) {
+	if req.SocketID == "" || req.Event == "" {
+		return http.StatusBadRequest, ErrMissingField
+	}
+
+	data, err := req.payload()
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return castStatus(h.serv.Socketcast(req.SocketID, req.Event, data))
+}
+
+func (h *handler) roomcast(req *Request) (int, error) {
+	if req.Room == "" || req.Event == "" {
+		return http.StatusBadRequest, ErrMissingField
+	}
+
+	data, err := req.payload()
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return castStatus(h.serv.Roomcast(req.Room, req.Event, data))
+}
+
+func (h *handler) broadcast(req *Request) (int, error) {
+	if req.Event == "" {
+		return http.StatusBadRequest, ErrMissingField
+	}
+
+	data, err := req.payload()
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return castStatus(h.serv.Broadcast(req.Event, data))
+}
+
+//castStatus returns the response status of a Socketcast, Roomcast, or Broadcast that returned err
+func castStatus(err error) (int, error) {
+	switch {
+	case err == nil:
+		return http.StatusOK, nil
+	case err == ss.ErrFanoutQuota:
+		return http.StatusTooManyRequests, err
+	default:
+		return http.StatusBadRequest, err
+	}
+}
+
+//payload converts req.Data into the value that will be emitted to the sockets
+func (req *Request) payload() (interface{}, error) {
+	if len(req.Data) == 0 {
+		req.Data = json.RawMessage("null")
+	}
+
+	var str string
+	isStr := json.Unmarshal(req.Data, &str) == nil
+
+	switch req.Type {
+	case "":
+		if isStr {
+			return str, nil
+		}
+		return req.Data, nil
+
+	case "S":
+		if !isStr {
+			return nil, errors.New("data must be a JSON string when type is S")
+		}
+		return str, nil
+
+	case "B":
+		if !isStr {
+			return nil, errors.New("data must be a base64 encoded JSON string when type is B")
+		}
+		return base64.StdEncoding.DecodeString(str)
+
+	case "J":
+		return req.Data, nil
+
+	default:
+		return nil, ErrBadDataType
+	}
+}
+
+func errResp(err error) map[string]string {
+	return map[string]string{"error": err.Error()}
+}
+
+//writeJSON encodes v before writing anything, so a value that can not be encoded, such as a socket
+//attribute holding a channel, results in a 500 instead of a truncated response with the wrong status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		log.Err.Println(err)
+		buf.Reset()
+		status = http.StatusInternalServerError
+		json.NewEncoder(&buf).Encode(errResp(ErrEncodeResponse))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
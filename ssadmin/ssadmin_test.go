@@ -0,0 +1,259 @@
+package ssadmin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	ss "github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/ssadmin"
+	"github.com/raz-varren/sacrificial-socket/ssproto"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+//do sends a request with body encoded as JSON to h, a nil body sends no body at all
+func do(h http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+	return w
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Errorf("expected status %d, got %d: %s", status, w.Code, w.Body)
+	}
+}
+
+//eventually polls cond until it returns true, failing the test if it does not within a second
+func eventually(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func hasRoom(s *ss.Socket, roomName string) bool {
+	for _, r := range s.GetRooms() {
+		if r == roomName {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSocketsAndRooms(t *testing.T) {
+	srv := sstest.NewServer(t, nil)
+	h := ssadmin.NewHandler(srv.SocketServer)
+
+	c := srv.Dial()
+	s, _ := srv.GetSocket(c.ID())
+	s.SetAttr("name", "alice")
+	s.Join("lobby")
+
+	w := do(h, http.MethodGet, "/sockets", nil)
+	expectStatus(t, w, http.StatusOK)
+
+	var sockets []ssadmin.SocketInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &sockets); err != nil {
+		t.Fatal(err)
+	}
+	if len(sockets) != 1 || sockets[0].ID != c.ID() {
+		t.Fatalf("expected only socket %s, got %+v", c.ID(), sockets)
+	}
+	if rooms := sockets[0].Rooms; len(rooms) != 2 || rooms[0] != ss.ReservedRoomPrefix+c.ID() || rooms[1] != "lobby" {
+		t.Errorf("expected the socket's rooms, got %v", rooms)
+	}
+	if name := sockets[0].Attributes["name"]; name != "alice" {
+		t.Errorf("expected the name attribute, got %v", name)
+	}
+
+	w = do(h, http.MethodGet, "/rooms", nil)
+	expectStatus(t, w, http.StatusOK)
+
+	var rooms []ssadmin.RoomInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &rooms); err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms) != 2 || rooms[1].Name != "lobby" || len(rooms[1].Members) != 1 || rooms[1].Members[0] != c.ID() {
+		t.Errorf("expected the lobby room with one member, got %+v", rooms)
+	}
+}
+
+func TestUnencodableAttribute(t *testing.T) {
+	srv := sstest.NewServer(t, nil)
+	h := ssadmin.NewHandler(srv.SocketServer)
+
+	c := srv.Dial()
+	s, _ := srv.GetSocket(c.ID())
+	s.SetAttr("updates", make(chan int))
+
+	w := do(h, http.MethodGet, "/sockets", nil)
+	expectStatus(t, w, http.StatusInternalServerError)
+
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected a JSON error response, got %q: %v", w.Body.String(), err)
+	}
+	if resp["error"] != ssadmin.ErrEncodeResponse.Error() {
+		t.Errorf("expected the encoding error, got %v", resp)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	h := ssadmin.NewHandler(ss.NewServer())
+
+	for _, path := range []string{"/sockets", "/rooms"} {
+		expectStatus(t, do(h, http.MethodPost, path, nil), http.StatusMethodNotAllowed)
+	}
+	for _, path := range []string{"/disconnect", "/join", "/leave", "/emit", "/roomcast", "/broadcast"} {
+		expectStatus(t, do(h, http.MethodGet, path, nil), http.StatusMethodNotAllowed)
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	h := ssadmin.NewHandler(ss.NewServer())
+
+	tests := []struct {
+		path string
+		body interface{}
+	}{
+		{"/disconnect", map[string]string{}},
+		{"/join", map[string]string{"socketId": "abc"}},
+		{"/join", map[string]string{"room": "lobby"}},
+		{"/join", map[string]string{"socketId": "abc", "room": ss.ReservedRoomPrefix + "def"}},
+		{"/leave", map[string]string{"socketId": "abc"}},
+		{"/emit", map[string]string{"socketId": "abc"}},
+		{"/emit", map[string]string{"event": "hello"}},
+		{"/roomcast", map[string]string{"event": "hello"}},
+		{"/broadcast", map[string]string{"data": "hi"}},
+		{"/broadcast", "not an object"},
+		{"/broadcast", map[string]string{"event": "hello", "type": "X"}},
+		{"/broadcast", map[string]interface{}{"event": "hello", "type": "S", "data": 1}},
+		{"/broadcast", map[string]interface{}{"event": "hello", "type": "B", "data": 1}},
+		{"/broadcast", map[string]interface{}{"event": "hello", "type": "B", "data": "not base64!"}},
+	}
+
+	for _, test := range tests {
+		w := do(h, http.MethodPost, test.path, test.body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %v: expected status 400, got %d", test.path, test.body, w.Code)
+		}
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	cluster := sstest.NewCluster(t, 2, nil)
+	h := ssadmin.NewHandler(cluster.Node(0).SocketServer)
+
+	local, remote := cluster.Node(0).Dial(), cluster.Node(1).Dial()
+
+	expectStatus(t, do(h, http.MethodPost, "/disconnect", map[string]string{"socketId": local.ID()}), http.StatusOK)
+	local.ExpectClosed()
+
+	expectStatus(t, do(h, http.MethodPost, "/disconnect", map[string]string{"socketId": remote.ID()}), http.StatusAccepted)
+	remote.ExpectClosed()
+}
+
+func TestJoinLeave(t *testing.T) {
+	cluster := sstest.NewCluster(t, 2, nil)
+	h := ssadmin.NewHandler(cluster.Node(0).SocketServer)
+
+	local, remote := cluster.Node(0).Dial(), cluster.Node(1).Dial()
+	ls, _ := cluster.Node(0).GetSocket(local.ID())
+	rs, _ := cluster.Node(1).GetSocket(remote.ID())
+
+	expectStatus(t, do(h, http.MethodPost, "/join", map[string]string{"socketId": local.ID(), "room": "lobby"}), http.StatusOK)
+	eventually(t, "local socket did not join lobby", func() bool { return hasRoom(ls, "lobby") })
+
+	expectStatus(t, do(h, http.MethodPost, "/join", map[string]string{"socketId": remote.ID(), "room": "lobby"}), http.StatusAccepted)
+	eventually(t, "remote socket did not join lobby", func() bool { return hasRoom(rs, "lobby") })
+
+	expectStatus(t, do(h, http.MethodPost, "/leave", map[string]string{"socketId": local.ID(), "room": "lobby"}), http.StatusOK)
+	eventually(t, "local socket did not leave lobby", func() bool { return !hasRoom(ls, "lobby") })
+
+	expectStatus(t, do(h, http.MethodPost, "/leave", map[string]string{"socketId": remote.ID(), "room": "lobby"}), http.StatusAccepted)
+	eventually(t, "remote socket did not leave lobby", func() bool { return !hasRoom(rs, "lobby") })
+}
+
+func TestEmitPayload(t *testing.T) {
+	srv := sstest.NewServer(t, nil)
+	h := ssadmin.NewHandler(srv.SocketServer)
+	c := srv.Dial()
+
+	tests := []struct {
+		typ      string
+		data     interface{}
+		dataType ssproto.DataType
+		raw      string
+	}{
+		{"", "hi", ssproto.TypeStr, "hi"},
+		{"", map[string]int{"a": 1}, ssproto.TypeJSON, `{"a":1}`},
+		{"S", "hi", ssproto.TypeStr, "hi"},
+		{"J", "hi", ssproto.TypeJSON, `"hi"`},
+		{"B", "AAH/", ssproto.TypeBin, "\x00\x01\xff"},
+	}
+
+	for _, test := range tests {
+		body := map[string]interface{}{"socketId": c.ID(), "event": "msg", "type": test.typ, "data": test.data}
+		expectStatus(t, do(h, http.MethodPost, "/emit", body), http.StatusOK)
+
+		msg := c.Expect("msg")
+		if msg.Type != test.dataType || string(msg.Raw) != test.raw {
+			t.Errorf("type %q: expected %q of type %v, got %q of type %v", test.typ, test.raw, test.dataType, msg.Raw, msg.Type)
+		}
+	}
+}
+
+func TestRoomcastBroadcast(t *testing.T) {
+	srv := sstest.NewServer(t, nil)
+	h := ssadmin.NewHandler(srv.SocketServer)
+
+	a, b := srv.Dial(), srv.Dial()
+	s, _ := srv.GetSocket(a.ID())
+	s.Join("lobby")
+
+	expectStatus(t, do(h, http.MethodPost, "/roomcast", map[string]string{"room": "lobby", "event": "news", "data": "lobby only"}), http.StatusOK)
+	if msg := a.Expect("news").String(); msg != "lobby only" {
+		t.Errorf("expected the roomcast, got %s", msg)
+	}
+	b.ExpectNone("news", 50*time.Millisecond)
+
+	expectStatus(t, do(h, http.MethodPost, "/broadcast", map[string]string{"event": "news", "data": "everyone"}), http.StatusOK)
+	for _, c := range []*sstest.Client{a, b} {
+		if msg := c.Expect("news").String(); msg != "everyone" {
+			t.Errorf("expected the broadcast, got %s", msg)
+		}
+	}
+}
+
+func TestFanoutQuota(t *testing.T) {
+	srv := sstest.NewServer(t, nil)
+	srv.SetFanoutQuota(ss.FanoutQuota{Recipients: 1})
+	h := ssadmin.NewHandler(srv.SocketServer)
+	c := srv.Dial()
+
+	body := map[string]string{"event": "news", "data": "hi"}
+	expectStatus(t, do(h, http.MethodPost, "/broadcast", body), http.StatusOK)
+	c.Expect("news")
+
+	w := do(h, http.MethodPost, "/broadcast", body)
+	expectStatus(t, w, http.StatusTooManyRequests)
+
+	var resp map[string]string
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["error"] != ss.ErrFanoutQuota.Error() {
+		t.Errorf("expected the fan-out quota error, got %v", resp)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Package ssauth provides HMAC signed JWTs and a ss.Authenticator that validates them during the websocket handshake.
+
+	serv := ss.NewServer()
+	serv.SetAuthenticator(ssauth.NewJWTAuthenticator(signingKey))
+
+	serv.On("whoami", func(s *ss.Socket, data []byte) {
+		s.Emit("whoami", s.Claims().Subject())
+	})
+
+Browsers can not set headers on websocket handshakes, so besides the Authorization header a JWTAuthenticator also looks
+for the token in the list of sub protocols requested by the client, in an entry starting with "bearer.". The sac-sock
+sub protocol must still be requested along with it:
+
+	var ss = new SS('wss://example.com/socket', {protocols: ['bearer.' + token]});
+
+The token can also be read from a query parameter or a cookie, see JWTAuthenticator.SetQueryParam and
+JWTAuthenticator.SetCookie.
+
+Tokens must be signed with HS256, HS384, or HS512. The "exp" and "nbf" claims are checked if present.
+*/
+package ssauth
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dvsekhvalnov/jose2go"
+	ss "github.com/raz-varren/sacrificial-socket"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	//ProtocolPrefix is the prefix of the Sec-WebSocket-Protocol entry carrying a token
+	ProtocolPrefix = "bearer."
+)
+
+var (
+	ErrNoToken          = errors.New("request did not provide a token")
+	ErrBadBearerValue   = errors.New("request provided an invalid Bearer value")
+	ErrBadAlgorithm     = errors.New("token is not signed with HS256, HS384, or HS512")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrBadClaims        = errors.New("token claims are not a JSON object")
+)
+
+//Sign returns claims as an HS256 signed JWT
+func Sign(claims ss.Claims, signingKey []byte) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return jose.Sign(string(data), jose.HS256, signingKey)
+}
+
+//Validate verifies the signature of token and returns its claims. An error is returned if the signature is
+//invalid, the token is not signed with HS256, HS384, or HS512, the "exp" claim is in the past, or the "nbf"
+//claim is in the future. leeway is the amount of clock skew allowed when checking "exp" and "nbf".
+func Validate(token string, signingKey []byte, leeway time.Duration) (ss.Claims, error) {
+	payload, _, err := jose.Decode(token, func(headers map[string]interface{}, payload string) interface{} {
+		switch headers["alg"] {
+		case jose.HS256, jose.HS384, jose.HS512:
+			return signingKey
+		default:
+			return ErrBadAlgorithm
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var claims ss.Claims
+	err = json.Unmarshal([]byte(payload), &claims)
+	if err != nil || claims == nil {
+		return nil, ErrBadClaims
+	}
+
+	now := time.Now()
+
+	if exp, ok := numericDate(claims, "exp"); ok && now.After(exp.Add(leeway)) {
+		return nil, ErrTokenExpired
+	}
+
+	if nbf, ok := numericDate(claims, "nbf"); ok && now.Add(leeway).Before(nbf) {
+		return nil, ErrTokenNotYetValid
+	}
+
+	return claims, nil
+}
+
+//numericDate returns the claim named key as a time, if it is a number of seconds since the unix epoch
+func numericDate(claims ss.Claims, key string) (time.Time, bool) {
+	secs, ok := claims[key].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(secs), 0), true
+}
+
+//JWTAuthenticator is a ss.Authenticator that validates JWTs signed with a shared key
+type JWTAuthenticator struct {
+	signingKey []byte
+	queryParam string
+	cookie     string
+	leeway     time.Duration
+	validate   func(ss.Claims) error
+}
+
+//NewJWTAuthenticator returns a *JWTAuthenticator that accepts tokens signed with signingKey sent either in
+//the Authorization header as a Bearer token, or in a Sec-WebSocket-Protocol entry starting with "bearer."
+func NewJWTAuthenticator(signingKey []byte) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		signingKey: signingKey,
+	}
+}
+
+//SetQueryParam makes the JWTAuthenticator also look for the token in the query parameter named name.
+//
+//Query strings tend to end up in access logs, so prefer the Authorization header or the Sec-WebSocket-Protocol
+//entry when possible.
+func (a *JWTAuthenticator) SetQueryParam(name string) {
+	a.queryParam = name
+}
+
+//SetCookie makes the JWTAuthenticator also look for the token in the cookie named name.
+//
+//Browsers send cookies with cross origin websocket handshakes, so only use cookies along with an OriginPolicy
+//that rejects untrusted origins, see SocketServer.SetOriginPolicy.
+func (a *JWTAuthenticator) SetCookie(name string) {
+	a.cookie = name
+}
+
+//SetLeeway sets the amount of clock skew allowed when checking the "exp" and "nbf" claims
+func (a *JWTAuthenticator) SetLeeway(leeway time.Duration) {
+	a.leeway = leeway
+}
+
+//SetValidator sets a function that is called with the claims of every token with a valid signature.
+//Returning an error rejects the handshake, which can be used to check claims like "iss" and "aud".
+func (a *JWTAuthenticator) SetValidator(validate func(ss.Claims) error) {
+	a.validate = validate
+}
+
+//Authenticate finds the request's token and returns its claims if it is valid
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (ss.Claims, error) {
+	token, err := a.token(r)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := Validate(token, a.signingKey, a.leeway)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.validate != nil {
+		err = a.validate(claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+//token returns the first token found in the Authorization header, the Sec-WebSocket-Protocol header,
+//the query parameter, or the cookie, in that order
+func (a *JWTAuthenticator) token(r *http.Request) (string, error) {
+	if authz := r.Header.Get("Authorization"); authz != "" {
+		parts := strings.SplitN(authz, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || strings.TrimSpace(parts[1]) == "" {
+			return "", ErrBadBearerValue
+		}
+		return strings.TrimSpace(parts[1]), nil
+	}
+
+	for _, h := range r.Header["Sec-Websocket-Protocol"] {
+		for _, p := range strings.Split(h, ",") {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, ProtocolPrefix) {
+				return p[len(ProtocolPrefix):], nil
+			}
+		}
+	}
+
+	if a.queryParam != "" {
+		if tok := r.URL.Query().Get(a.queryParam); tok != "" {
+			return tok, nil
+		}
+	}
+
+	if a.cookie != "" {
+		if c, err := r.Cookie(a.cookie); err == nil && c.Value != "" {
+			return c.Value, nil
+		}
+	}
+
+	return "", ErrNoToken
+}
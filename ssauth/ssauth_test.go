@@ -0,0 +1,124 @@
+package ssauth
+
+import (
+	"github.com/dvsekhvalnov/jose2go"
+	"github.com/gorilla/websocket"
+	ss "github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("test signing key")
+
+func sign(t *testing.T, claims ss.Claims) string {
+	tok, err := Sign(claims, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Now().Unix()
+	none, _ := jose.Sign(`{"sub":"mallory"}`, jose.NONE, nil)
+
+	tests := []struct {
+		name  string
+		token string
+		key   []byte
+		err   error
+	}{
+		{"valid", sign(t, ss.Claims{"sub": "alice", "exp": now + 60}), testKey, nil},
+		{"no exp", sign(t, ss.Claims{"sub": "alice"}), testKey, nil},
+		{"expired", sign(t, ss.Claims{"sub": "alice", "exp": now - 60}), testKey, ErrTokenExpired},
+		{"not yet valid", sign(t, ss.Claims{"sub": "alice", "nbf": now + 60}), testKey, ErrTokenNotYetValid},
+		{"none algorithm", none, testKey, ErrBadAlgorithm},
+	}
+
+	for _, tt := range tests {
+		_, err := Validate(tt.token, tt.key, 0)
+		if err != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	_, err := Validate(sign(t, ss.Claims{"sub": "alice"}), []byte("wrong key"), 0)
+	if err == nil {
+		t.Error("expected a token signed with a different key to be rejected")
+	}
+
+	_, err = Validate(sign(t, ss.Claims{"exp": now - 5}), testKey, 10*time.Second)
+	if err != nil {
+		t.Errorf("expected leeway to allow a recently expired token, got %v", err)
+	}
+}
+
+func TestTokenSources(t *testing.T) {
+	tok := sign(t, ss.Claims{"sub": "alice"})
+
+	a := NewJWTAuthenticator(testKey)
+	a.SetQueryParam("access_token")
+	a.SetCookie("ss_token")
+
+	tests := []struct {
+		name  string
+		setup func(r *http.Request)
+		err   error
+	}{
+		{"authorization header", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+tok) }, nil},
+		{"bad authorization header", func(r *http.Request) { r.Header.Set("Authorization", "Basic "+tok) }, ErrBadBearerValue},
+		{"sub protocol", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Protocol", "sac-sock, bearer."+tok) }, nil},
+		{"query param", func(r *http.Request) { r.URL.RawQuery = "access_token=" + tok }, nil},
+		{"cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "ss_token", Value: tok}) }, nil},
+		{"no token", func(r *http.Request) {}, ErrNoToken},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/socket", nil)
+		tt.setup(r)
+
+		claims, err := a.Authenticate(r)
+		if err != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.err, err)
+			continue
+		}
+		if err == nil && claims.Subject() != "alice" {
+			t.Errorf("%s: expected subject alice, got %q", tt.name, claims.Subject())
+		}
+	}
+}
+
+func TestSocketClaims(t *testing.T) {
+	serv := ss.NewServer()
+	serv.SetAuthenticator(NewJWTAuthenticator(testKey))
+	serv.On("whoami", func(s *ss.Socket, data []byte) {
+		s.Emit("whoami", s.Claims().Subject())
+	})
+	srv := sstest.NewServer(t, serv)
+
+	_, err := sstest.Dial(srv.URL, nil)
+	if err != websocket.ErrBadHandshake {
+		t.Fatalf("expected a handshake without a token to fail, got %v", err)
+	}
+
+	c := srv.DialHeader(http.Header{"Authorization": []string{"Bearer " + sign(t, ss.Claims{"sub": "alice"})}})
+	c.Emit("whoami", "")
+	if msg := c.Expect("whoami"); msg.String() != "alice" {
+		t.Errorf("expected alice, got %s", msg)
+	}
+
+	//browsers send the token as a sub protocol
+	d := &websocket.Dialer{Subprotocols: []string{ss.SubProtocol, ProtocolPrefix + sign(t, ss.Claims{"sub": "bob"})}}
+	ws, _, err := d.Dial(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	if p := ws.Subprotocol(); p != ss.SubProtocol {
+		t.Errorf("expected the %s sub protocol to be negotiated, got %q", ss.SubProtocol, p)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Package ssproto implements the framing used by the sac-sock websocket sub protocol.
+
+Every sac-sock frame is a single websocket message laid out like so:
+
+	<event name> SOH <data type> STX <payload>
+
+where SOH and STX are the ASCII "start of header" and "start of text" control bytes, and data type is a single
+byte describing the payload: 'S' for a string, 'B' for binary data, or 'J' for JSON.
+
+Older clients leave out the header entirely and send frames that look like:
+
+	<event name> STX <payload>
+
+Decode accepts both forms. Frames without a header are returned with the TypeUnknown data type, it is up to
+the caller to decide what the payload is, usually based on the websocket message type that carried it.
+*/
+package ssproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"unicode/utf8"
+)
+
+const ( //                       ASCII chars
+	StartOfHeaderByte byte = 1 //SOH
+	StartOfDataByte   byte = 2 //STX
+)
+
+//DataType describes the kind of payload carried by a frame
+type DataType byte
+
+const (
+	//TypeUnknown is returned by Decode for frames that do not carry a data type header
+	TypeUnknown DataType = 0
+
+	TypeStr  DataType = 'S'
+	TypeBin  DataType = 'B'
+	TypeJSON DataType = 'J'
+)
+
+var (
+	ErrEmptyFrame     = errors.New("empty frame")
+	ErrNoEventName    = errors.New("frame has no event name")
+	ErrBadEventName   = errors.New("event name contains invalid characters")
+	ErrNoStartOfData  = errors.New("frame is missing the start of data byte")
+	ErrBadDataType    = errors.New("frame has an invalid data type header")
+	ErrBadPayloadType = errors.New("payload type does not match data type")
+)
+
+//String returns the single character representation of t, or "?" if t is not
+//a known DataType
+func (t DataType) String() string {
+	switch t {
+	case TypeStr, TypeBin, TypeJSON:
+		return string(rune(t))
+	default:
+		return "?"
+	}
+}
+
+//Valid returns true if t is one of TypeStr, TypeBin, or TypeJSON
+func (t DataType) Valid() bool {
+	switch t {
+	case TypeStr, TypeBin, TypeJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+//Encode combines eventName and data into a sac-sock frame.
+//
+//A string is sent as TypeStr, a []byte as TypeBin, and anything else is
+//marshaled to JSON and sent as TypeJSON. The DataType used is returned along with the frame.
+func Encode(eventName string, data interface{}) ([]byte, DataType, error) {
+	err := CheckEventName(eventName)
+	if err != nil {
+		return nil, TypeUnknown, err
+	}
+
+	switch d := data.(type) {
+	case string:
+		return buildFrame(eventName, TypeStr, []byte(d)), TypeStr, nil
+
+	case []byte:
+		return buildFrame(eventName, TypeBin, d), TypeBin, nil
+
+	default:
+		jsonData, err := json.Marshal(d)
+		if err != nil {
+			return nil, TypeJSON, err
+		}
+		return buildFrame(eventName, TypeJSON, jsonData), TypeJSON, nil
+	}
+}
+
+//EncodeRaw builds a sac-sock frame from an already encoded payload. payload is
+//written to the frame as is, but if dataType is TypeJSON it must be valid JSON.
+func EncodeRaw(eventName string, dataType DataType, payload []byte) ([]byte, error) {
+	err := CheckEventName(eventName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dataType.Valid() {
+		return nil, ErrBadDataType
+	}
+
+	if dataType == TypeJSON && !json.Valid(payload) {
+		return nil, ErrBadPayloadType
+	}
+
+	return buildFrame(eventName, dataType, payload), nil
+}
+
+func buildFrame(eventName string, dataType DataType, payload []byte) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(eventName)+len(payload)+3))
+	buf.WriteString(eventName)
+	buf.WriteByte(StartOfHeaderByte)
+	buf.WriteByte(byte(dataType))
+	buf.WriteByte(StartOfDataByte)
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+//Decode splits a sac-sock frame into its event name, data type, and payload.
+//
+//The returned payload shares its backing array with frame.
+func Decode(frame []byte) (eventName string, dataType DataType, payload []byte, err error) {
+	if len(frame) == 0 {
+		return "", TypeUnknown, nil, ErrEmptyFrame
+	}
+
+	nameEnd := bytes.IndexAny(frame, string([]byte{StartOfHeaderByte, StartOfDataByte}))
+	if nameEnd == -1 {
+		return "", TypeUnknown, nil, ErrNoStartOfData
+	}
+
+	eventName = string(frame[:nameEnd])
+	err = CheckEventName(eventName)
+	if err != nil {
+		return "", TypeUnknown, nil, err
+	}
+
+	rest := frame[nameEnd:]
+	dataType = TypeUnknown
+
+	if rest[0] == StartOfHeaderByte {
+		dataIdx := bytes.IndexByte(rest, StartOfDataByte)
+		if dataIdx == -1 {
+			return "", TypeUnknown, nil, ErrNoStartOfData
+		}
+
+		header := rest[1:dataIdx]
+		if len(header) != 1 || !DataType(header[0]).Valid() {
+			return "", TypeUnknown, nil, ErrBadDataType
+		}
+
+		dataType = DataType(header[0])
+		rest = rest[dataIdx:]
+	}
+
+	//rest[0] is now guaranteed to be StartOfDataByte
+	return eventName, dataType, rest[1:], nil
+}
+
+//CheckEventName returns an error if eventName can not be used in a sac-sock frame.
+//Event names must be non empty valid UTF-8 and may not contain any control characters.
+func CheckEventName(eventName string) error {
+	if eventName == "" {
+		return ErrNoEventName
+	}
+
+	if !utf8.ValidString(eventName) {
+		return ErrBadEventName
+	}
+
+	for _, r := range eventName {
+		if r < 0x20 || r == 0x7f {
+			return ErrBadEventName
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package ssproto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   string
+		event   string
+		dType   DataType
+		payload string
+		err     error
+	}{
+		{"string", "echo\x01S\x02hello", "echo", TypeStr, "hello", nil},
+		{"binary", "echo\x01B\x02\x00\x01\x02", "echo", TypeBin, "\x00\x01\x02", nil},
+		{"json", "echo\x01J\x02{\"a\":1}", "echo", TypeJSON, "{\"a\":1}", nil},
+		{"legacy", "echo\x02hello", "echo", TypeUnknown, "hello", nil},
+		{"empty payload", "echo\x01S\x02", "echo", TypeStr, "", nil},
+		{"payload with control bytes", "echo\x01B\x02\x01\x02\x01", "echo", TypeBin, "\x01\x02\x01", nil},
+		{"empty frame", "", "", TypeUnknown, "", ErrEmptyFrame},
+		{"no stx", "echo", "", TypeUnknown, "", ErrNoStartOfData},
+		{"header without stx", "echo\x01S", "", TypeUnknown, "", ErrNoStartOfData},
+		{"no event name", "\x02hello", "", TypeUnknown, "", ErrNoEventName},
+		{"no event name with header", "\x01S\x02hello", "", TypeUnknown, "", ErrNoEventName},
+		{"control byte in event name", "ec\x03ho\x02hello", "", TypeUnknown, "", ErrBadEventName},
+		{"invalid utf8 event name", "ec\xffho\x02hello", "", TypeUnknown, "", ErrBadEventName},
+		{"unknown data type", "echo\x01X\x02hello", "", TypeUnknown, "", ErrBadDataType},
+		{"empty header", "echo\x01\x02hello", "", TypeUnknown, "", ErrBadDataType},
+		{"long header", "echo\x01SB\x02hello", "", TypeUnknown, "", ErrBadDataType},
+	}
+
+	for _, tt := range tests {
+		event, dType, payload, err := Decode([]byte(tt.frame))
+		if err != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.err, err)
+			continue
+		}
+		if event != tt.event || dType != tt.dType || string(payload) != tt.payload {
+			t.Errorf("%s: expected (%q, %s, %q), got (%q, %s, %q)", tt.name, tt.event, tt.dType, tt.payload, event, dType, payload)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		data  interface{}
+		frame string
+		dType DataType
+		err   error
+	}{
+		{"string", "echo", "hello", "echo\x01S\x02hello", TypeStr, nil},
+		{"binary", "echo", []byte{0, 1, 2}, "echo\x01B\x02\x00\x01\x02", TypeBin, nil},
+		{"json", "echo", map[string]int{"a": 1}, "echo\x01J\x02{\"a\":1}", TypeJSON, nil},
+		{"nil", "echo", nil, "echo\x01J\x02null", TypeJSON, nil},
+		{"no event name", "", "hello", "", TypeUnknown, ErrNoEventName},
+		{"control byte in event name", "ec\x01ho", "hello", "", TypeUnknown, ErrBadEventName},
+	}
+
+	for _, tt := range tests {
+		frame, dType, err := Encode(tt.event, tt.data)
+		if err != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.err, err)
+			continue
+		}
+		if string(frame) != tt.frame || dType != tt.dType {
+			t.Errorf("%s: expected (%q, %s), got (%q, %s)", tt.name, tt.frame, tt.dType, frame, dType)
+		}
+	}
+
+	_, _, err := Encode("echo", func() {})
+	if err == nil {
+		t.Error("expected an error when encoding data that can not be marshaled to JSON")
+	}
+}
+
+func TestEncodeRaw(t *testing.T) {
+	_, err := EncodeRaw("echo", TypeJSON, []byte("{bad json"))
+	if err != ErrBadPayloadType {
+		t.Errorf("expected %v, got %v", ErrBadPayloadType, err)
+	}
+
+	_, err = EncodeRaw("echo", TypeUnknown, []byte("hello"))
+	if err != ErrBadDataType {
+		t.Errorf("expected %v, got %v", ErrBadDataType, err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte("echo\x01S\x02hello"))
+	f.Add([]byte("echo\x01B\x02\x00\x01\x02"))
+	f.Add([]byte("echo\x01J\x02{\"a\":1}"))
+	f.Add([]byte("echo\x02hello"))
+	f.Add([]byte("\x01\x02"))
+	f.Add([]byte("echo\x01"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		event, dType, payload, err := Decode(frame)
+		if err != nil {
+			return
+		}
+
+		if CheckEventName(event) != nil {
+			t.Fatalf("decoded an invalid event name %q", event)
+		}
+
+		if dType == TypeUnknown {
+			return
+		}
+
+		reframed, err := EncodeRaw(event, dType, payload)
+		if dType == TypeJSON && err == ErrBadPayloadType {
+			return
+		}
+		if err != nil {
+			t.Fatalf("failed to re-encode decoded frame %q: %v", frame, err)
+		}
+		if !bytes.Equal(reframed, frame) {
+			t.Fatalf("re-encoded frame %q does not match original %q", reframed, frame)
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("echo", []byte("hello"), true)
+	f.Add("echo", []byte{0, 1, 2}, false)
+	f.Add("", []byte("hello"), true)
+	f.Add("ev\x02nt", []byte("\x01\x02"), false)
+
+	f.Fuzz(func(t *testing.T, event string, data []byte, asString bool) {
+		var in interface{} = data
+		if asString {
+			in = string(data)
+		}
+
+		frame, dType, err := Encode(event, in)
+		if err != nil {
+			if CheckEventName(event) == nil {
+				t.Fatalf("unexpected encode error for valid event name %q: %v", event, err)
+			}
+			return
+		}
+
+		outEvent, outType, payload, err := Decode(frame)
+		if err != nil {
+			t.Fatalf("failed to decode encoded frame %q: %v", frame, err)
+		}
+		if outEvent != event || outType != dType || !bytes.Equal(payload, data) {
+			t.Fatalf("round trip mismatch: got (%q, %s, %q), expected (%q, %s, %q)", outEvent, outType, payload, event, dType, data)
+		}
+	})
+}
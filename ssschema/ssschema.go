@@ -0,0 +1,580 @@
+/*
+Package ssschema implements a subset of JSON Schema for validating event payloads with SocketServer.SetSchema.
+
+	serv.SetSchema("chat", ssschema.MustCompile(`{
+		"type": "object",
+		"properties": {
+			"room": {"type": "string", "minLength": 1},
+			"message": {"type": "string", "maxLength": 500}
+		},
+		"required": ["room", "message"],
+		"additionalProperties": false
+	}`))
+
+The following keywords are supported:
+
+	type, enum, const
+	allOf, anyOf, oneOf, not
+	properties, required, additionalProperties, minProperties, maxProperties
+	items, minItems, maxItems, uniqueItems
+	minLength, maxLength, pattern
+	minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf
+
+exclusiveMinimum and exclusiveMaximum take numbers, as they do in draft 6 and later. pattern uses Go's regexp syntax,
+which covers the ECMA 262 features most schemas use, but not lookarounds or backreferences. Annotation keywords like
+title, description, default, examples, format, and $schema are ignored. Keywords that change what a schema accepts but are
+not supported, such as $ref or patternProperties, cause Compile to return an error rather than being silently ignored.
+*/
+package ssschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//unsupported lists the keywords that Compile refuses, since ignoring them would accept values the schema does not allow
+var unsupported = []string{
+	"$ref", "$defs", "definitions", "patternProperties", "propertyNames", "dependencies", "dependentRequired",
+	"dependentSchemas", "if", "then", "else", "contains", "minContains", "maxContains", "prefixItems",
+	"additionalItems", "unevaluatedItems", "unevaluatedProperties",
+}
+
+//Schema is a compiled JSON Schema. A Schema is safe for concurrent use by multiple goroutines.
+type Schema struct {
+	always *bool //set for the boolean schemas true and false
+
+	types    []string
+	enum     []interface{}
+	hasConst bool
+	constVal interface{}
+
+	allOf []*Schema
+	anyOf []*Schema
+	oneOf []*Schema
+	not   *Schema
+
+	properties    map[string]*Schema
+	required      []string
+	additional    *Schema
+	minProperties *int
+	maxProperties *int
+
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+}
+
+//ValidationError describes why a value does not match a Schema
+type ValidationError struct {
+	//Path is the JSON Pointer to the invalid part of the value, it is empty if the value itself is invalid
+	Path string
+
+	//Message describes the problem
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+//Compile parses a JSON Schema
+func Compile(schema []byte) (*Schema, error) {
+	var v interface{}
+	err := json.Unmarshal(schema, &v)
+	if err != nil {
+		return nil, err
+	}
+	return compile(v, "")
+}
+
+//MustCompile is like Compile but panics if the schema can not be compiled. It simplifies
+//the initialization of global variables holding schemas.
+func MustCompile(schema string) *Schema {
+	s, err := Compile([]byte(schema))
+	if err != nil {
+		panic("ssschema: Compile: " + err.Error())
+	}
+	return s
+}
+
+func compile(v interface{}, path string) (*Schema, error) {
+	if b, ok := v.(bool); ok {
+		return &Schema{always: &b}, nil
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, compileErr(path, "a schema must be an object or a boolean")
+	}
+
+	for _, kw := range unsupported {
+		if _, exists := m[kw]; exists {
+			return nil, compileErr(path, "the "+kw+" keyword is not supported")
+		}
+	}
+
+	s := &Schema{}
+	var err error
+
+	if t, exists := m["type"]; exists {
+		s.types, err = stringOrList(t)
+		if err != nil {
+			return nil, compileErr(path+"/type", err.Error())
+		}
+		for _, typ := range s.types {
+			switch typ {
+			case "null", "boolean", "object", "array", "number", "integer", "string":
+			default:
+				return nil, compileErr(path+"/type", "unknown type "+strconv.Quote(typ))
+			}
+		}
+	}
+
+	if e, exists := m["enum"]; exists {
+		list, ok := e.([]interface{})
+		if !ok {
+			return nil, compileErr(path+"/enum", "must be an array")
+		}
+		s.enum = list
+	}
+
+	s.constVal, s.hasConst = m["const"]
+
+	for _, kw := range []string{"allOf", "anyOf", "oneOf"} {
+		list, exists := m[kw]
+		if !exists {
+			continue
+		}
+
+		schemas, ok := list.([]interface{})
+		if !ok || len(schemas) == 0 {
+			return nil, compileErr(path+"/"+kw, "must be a non empty array")
+		}
+
+		var compiled []*Schema
+		for i, sub := range schemas {
+			c, err := compile(sub, path+"/"+kw+"/"+strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			compiled = append(compiled, c)
+		}
+
+		switch kw {
+		case "allOf":
+			s.allOf = compiled
+		case "anyOf":
+			s.anyOf = compiled
+		case "oneOf":
+			s.oneOf = compiled
+		}
+	}
+
+	if n, exists := m["not"]; exists {
+		s.not, err = compile(n, path+"/not")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p, exists := m["properties"]; exists {
+		props, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, compileErr(path+"/properties", "must be an object")
+		}
+
+		s.properties = make(map[string]*Schema, len(props))
+		for name, sub := range props {
+			s.properties[name], err = compile(sub, path+"/properties/"+escape(name))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if r, exists := m["required"]; exists {
+		s.required, err = stringOrList(r)
+		if _, isStr := r.(string); err != nil || isStr {
+			return nil, compileErr(path+"/required", "must be an array of strings")
+		}
+	}
+
+	if a, exists := m["additionalProperties"]; exists {
+		s.additional, err = compile(a, path+"/additionalProperties")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if i, exists := m["items"]; exists {
+		s.items, err = compile(i, path+"/items")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if u, exists := m["uniqueItems"]; exists {
+		s.uniqueItems, ok = u.(bool)
+		if !ok {
+			return nil, compileErr(path+"/uniqueItems", "must be a boolean")
+		}
+	}
+
+	ints := map[string]**int{
+		"minProperties": &s.minProperties,
+		"maxProperties": &s.maxProperties,
+		"minItems":      &s.minItems,
+		"maxItems":      &s.maxItems,
+		"minLength":     &s.minLength,
+		"maxLength":     &s.maxLength,
+	}
+	for kw, dst := range ints {
+		if n, exists := m[kw]; exists {
+			f, ok := n.(float64)
+			if !ok || f < 0 || f != math.Trunc(f) {
+				return nil, compileErr(path+"/"+kw, "must be a non negative integer")
+			}
+			i := int(f)
+			*dst = &i
+		}
+	}
+
+	floats := map[string]**float64{
+		"minimum":          &s.minimum,
+		"maximum":          &s.maximum,
+		"exclusiveMinimum": &s.exclusiveMinimum,
+		"exclusiveMaximum": &s.exclusiveMaximum,
+		"multipleOf":       &s.multipleOf,
+	}
+	for kw, dst := range floats {
+		if n, exists := m[kw]; exists {
+			f, ok := n.(float64)
+			if !ok {
+				return nil, compileErr(path+"/"+kw, "must be a number")
+			}
+			*dst = &f
+		}
+	}
+	if s.multipleOf != nil && *s.multipleOf <= 0 {
+		return nil, compileErr(path+"/multipleOf", "must be greater than 0")
+	}
+
+	if p, exists := m["pattern"]; exists {
+		str, ok := p.(string)
+		if !ok {
+			return nil, compileErr(path+"/pattern", "must be a string")
+		}
+		s.pattern, err = regexp.Compile(str)
+		if err != nil {
+			return nil, compileErr(path+"/pattern", err.Error())
+		}
+	}
+
+	return s, nil
+}
+
+//ValidateJSON validates the JSON encoded value data
+func (s *Schema) ValidateJSON(data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return &ValidationError{Message: "invalid JSON: " + err.Error()}
+	}
+	return s.Validate(v)
+}
+
+//Validate validates v, which must be a value produced by json.Unmarshal into an interface{}: nil, bool,
+//float64, string, []interface{}, or map[string]interface{}. A *ValidationError is returned if v does not
+//match the schema.
+func (s *Schema) Validate(v interface{}) error {
+	err := s.validate(v, "")
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Schema) validate(v interface{}, path string) *ValidationError {
+	if s.always != nil {
+		if !*s.always {
+			return invalid(path, "no value is allowed here")
+		}
+		return nil
+	}
+
+	if len(s.types) > 0 && !s.matchesType(v) {
+		return invalid(path, "expected "+strings.Join(s.types, " or ")+", got "+typeOf(v))
+	}
+
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return invalid(path, "value is not one of the allowed values")
+		}
+	}
+
+	if s.hasConst && !reflect.DeepEqual(v, s.constVal) {
+		return invalid(path, "value does not match the constant value")
+	}
+
+	for _, sub := range s.allOf {
+		if err := sub.validate(v, path); err != nil {
+			return err
+		}
+	}
+
+	if s.anyOf != nil {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.validate(v, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return invalid(path, "value does not match any of the schemas in anyOf")
+		}
+	}
+
+	if s.oneOf != nil {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if sub.validate(v, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return invalid(path, "value matches "+strconv.Itoa(matches)+" of the schemas in oneOf, expected exactly 1")
+		}
+	}
+
+	if s.not != nil && s.not.validate(v, path) == nil {
+		return invalid(path, "value matches the schema in not")
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return s.validateObject(val, path)
+	case []interface{}:
+		return s.validateArray(val, path)
+	case string:
+		return s.validateString(val, path)
+	case float64:
+		return s.validateNumber(val, path)
+	}
+
+	return nil
+}
+
+func (s *Schema) validateObject(obj map[string]interface{}, path string) *ValidationError {
+	if s.minProperties != nil && len(obj) < *s.minProperties {
+		return invalid(path, fmt.Sprintf("expected at least %d properties, got %d", *s.minProperties, len(obj)))
+	}
+	if s.maxProperties != nil && len(obj) > *s.maxProperties {
+		return invalid(path, fmt.Sprintf("expected at most %d properties, got %d", *s.maxProperties, len(obj)))
+	}
+
+	for _, name := range s.required {
+		if _, exists := obj[name]; !exists {
+			return invalid(path, "missing required property "+strconv.Quote(name))
+		}
+	}
+
+	//check the properties in a stable order so the same value always produces the same error
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propPath := path + "/" + escape(name)
+
+		if sub, exists := s.properties[name]; exists {
+			if err := sub.validate(obj[name], propPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if s.additional != nil {
+			if s.additional.always != nil && !*s.additional.always {
+				return invalid(path, "property "+strconv.Quote(name)+" is not allowed")
+			}
+			if err := s.additional.validate(obj[name], propPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateArray(arr []interface{}, path string) *ValidationError {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		return invalid(path, fmt.Sprintf("expected at least %d items, got %d", *s.minItems, len(arr)))
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		return invalid(path, fmt.Sprintf("expected at most %d items, got %d", *s.maxItems, len(arr)))
+	}
+
+	if s.uniqueItems {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if reflect.DeepEqual(arr[i], arr[j]) {
+					return invalid(path, fmt.Sprintf("items %d and %d are equal", i, j))
+				}
+			}
+		}
+	}
+
+	if s.items != nil {
+		for i, item := range arr {
+			if err := s.items.validate(item, path+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateString(str string, path string) *ValidationError {
+	n := utf8.RuneCountInString(str)
+	if s.minLength != nil && n < *s.minLength {
+		return invalid(path, fmt.Sprintf("expected at least %d characters, got %d", *s.minLength, n))
+	}
+	if s.maxLength != nil && n > *s.maxLength {
+		return invalid(path, fmt.Sprintf("expected at most %d characters, got %d", *s.maxLength, n))
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		return invalid(path, "does not match the pattern "+strconv.Quote(s.pattern.String()))
+	}
+	return nil
+}
+
+func (s *Schema) validateNumber(f float64, path string) *ValidationError {
+	num := strconv.FormatFloat(f, 'g', -1, 64)
+
+	if s.minimum != nil && f < *s.minimum {
+		return invalid(path, num+" is less than the minimum of "+fmtFloat(*s.minimum))
+	}
+	if s.maximum != nil && f > *s.maximum {
+		return invalid(path, num+" is greater than the maximum of "+fmtFloat(*s.maximum))
+	}
+	if s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum {
+		return invalid(path, num+" must be greater than "+fmtFloat(*s.exclusiveMinimum))
+	}
+	if s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum {
+		return invalid(path, num+" must be less than "+fmtFloat(*s.exclusiveMaximum))
+	}
+	if s.multipleOf != nil {
+		q := f / *s.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			return invalid(path, num+" is not a multiple of "+fmtFloat(*s.multipleOf))
+		}
+	}
+	return nil
+}
+
+func (s *Schema) matchesType(v interface{}) bool {
+	actual := typeOf(v)
+	for _, t := range s.types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+//typeOf returns the JSON Schema type of v, numbers without a fractional part are integers
+func typeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func stringOrList(v interface{}) ([]string, error) {
+	switch val := v.(type) {
+	case string:
+		return []string{val}, nil
+	case []interface{}:
+		list := make([]string, 0, len(val))
+		for _, item := range val {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a string or an array of strings")
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("must be a string or an array of strings")
+	}
+}
+
+//escape escapes a property name for use in a JSON Pointer
+func escape(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
+
+func fmtFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func invalid(path, msg string) *ValidationError {
+	return &ValidationError{Path: path, Message: msg}
+}
+
+func compileErr(path, msg string) error {
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Errorf("schema %s: %s", path, msg)
+}
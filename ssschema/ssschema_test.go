@@ -0,0 +1,84 @@
+package ssschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	s := MustCompile(`{
+		"type": "object",
+		"properties": {
+			"room": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"},
+			"count": {"type": "integer", "minimum": 1, "exclusiveMaximum": 10},
+			"tags": {"type": "array", "items": {"enum": ["a", "b"]}, "maxItems": 2, "uniqueItems": true},
+			"to": {"anyOf": [{"type": "null"}, {"type": "string"}]}
+		},
+		"required": ["room"],
+		"additionalProperties": false
+	}`)
+
+	tests := []struct {
+		json string
+		path string
+	}{
+		{`{"room": "lobby"}`, ""},
+		{`{"room": "lobby", "count": 9, "tags": ["a", "b"], "to": null}`, ""},
+		{`{"room": "lobby", "count": 2.0}`, ""},
+		{`"lobby"`, "-"},
+		{`{}`, "-"},
+		{`{"room": ""}`, "/room"},
+		{`{"room": "Lobby"}`, "/room"},
+		{`{"room": "lobby", "count": 1.5}`, "/count"},
+		{`{"room": "lobby", "count": 10}`, "/count"},
+		{`{"room": "lobby", "tags": ["a", "a"]}`, "/tags"},
+		{`{"room": "lobby", "tags": ["c"]}`, "/tags/0"},
+		{`{"room": "lobby", "to": 5}`, "/to"},
+		{`{"room": "lobby", "extra": true}`, "-"},
+		{`{"room": `, "-"},
+	}
+
+	for _, tt := range tests {
+		err := s.ValidateJSON([]byte(tt.json))
+		if tt.path == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.json, err)
+			}
+			continue
+		}
+
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected a *ValidationError, got %v", tt.json, err)
+			continue
+		}
+		if tt.path != "-" && verr.Path != tt.path {
+			t.Errorf("%s: expected an error at %s, got %v", tt.json, tt.path, verr)
+		}
+		if tt.path == "-" && verr.Path != "" {
+			t.Errorf("%s: expected an error for the whole value, got %v", tt.json, verr)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`{"type": "strin"}`, "unknown type"},
+		{`{"$ref": "#/definitions/user"}`, "$ref"},
+		{`{"properties": {"a": {"patternProperties": {}}}}`, "/properties/a"},
+		{`{"minLength": -1}`, "non negative integer"},
+		{`{"pattern": "("}`, "/pattern"},
+		{`{"anyOf": []}`, "non empty array"},
+		{`[]`, "must be an object or a boolean"},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile([]byte(tt.schema))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.schema, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package sstest
+
+import (
+	ss "github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/backend/ssmem"
+	"testing"
+)
+
+//Cluster is a group of Servers that share an in memory MultihomeBackend
+type Cluster struct {
+	Nodes []*Server
+
+	//Broker connects the Nodes, use it to inject latency or message loss between them
+	Broker *ssmem.Broker
+}
+
+//NewCluster starts n Servers connected to each other through an ssmem MultihomeBackend.
+//
+//newServer is called once for each node and should return a SocketServer with its event handlers
+//already registered. If newServer is nil each node uses a plain SocketServer.
+//
+//Messages travel between the nodes asynchronously, just like they would with a real MultihomeBackend,
+//so a roomcast sent from one node may not have been delivered to the other nodes when Roomcast returns.
+func NewCluster(t testing.TB, n int, newServer func() *ss.SocketServer) *Cluster {
+	t.Helper()
+
+	if newServer == nil {
+		newServer = ss.NewServer
+	}
+
+	c := &Cluster{Broker: ssmem.NewBroker()}
+
+	for i := 0; i < n; i++ {
+		serv := newServer()
+		serv.SetMultihomeBackend(c.Broker.NewBackend())
+		c.Nodes = append(c.Nodes, NewServer(t, serv))
+	}
+
+	return c
+}
+
+//Node returns the i'th Server in the cluster
+func (c *Cluster) Node(i int) *Server {
+	return c.Nodes[i]
+}
+
+//Close closes every Server in the cluster
+func (c *Cluster) Close() {
+	for _, srv := range c.Nodes {
+		srv.Close()
+	}
+}
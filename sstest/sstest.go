@@ -0,0 +1,359 @@
+/*
+Package sstest provides utilities for testing code built on top of Sacrificial-Socket.
+
+NewServer starts a SocketServer on an httptest.Server, and Server.Dial returns a Client that is connected to it
+using the sac-sock sub protocol. Clients can emit events and wait for the events sent to them:
+
+	func TestEcho(t *testing.T) {
+		serv := ss.NewServer()
+		serv.On("echo", func(s *ss.Socket, data []byte) {
+			s.Emit("echo", string(data))
+		})
+
+		c := sstest.NewServer(t, serv).Dial()
+		c.Emit("echo", "hello")
+
+		if msg := c.Expect("echo"); msg.String() != "hello" {
+			t.Errorf("expected hello, got %s", msg)
+		}
+	}
+
+NewCluster starts several SocketServers that share an ssmem MultihomeBackend, which makes it possible to test
+roomcasts, broadcasts, and socket control messages across multiple servers without any external services.
+
+Every Server, Cluster, and Client is closed automatically when the test that created it completes.
+*/
+package sstest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	ss "github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/ssproto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	//DefaultTimeout is how long a Client will wait for an expected event before failing the test
+	DefaultTimeout = 2 * time.Second
+)
+
+var (
+	ErrTimeout      = errors.New("timed out waiting for event")
+	ErrClientClosed = errors.New("client connection is closed")
+)
+
+//Server is a SocketServer running on an httptest.Server
+type Server struct {
+	*ss.SocketServer
+
+	//HTTP is the underlying httptest.Server
+	HTTP *httptest.Server
+
+	//URL is the websocket URL of the SocketServer, in the form ws://127.0.0.1:port/socket
+	URL string
+
+	t         testing.TB
+	closeOnce *sync.Once
+}
+
+//NewServer starts serv on a new httptest.Server at the path /socket. If serv is nil a new SocketServer is created.
+//The Server is closed when t completes. NewServer does not register any event functions on serv, so the
+//SocketServer under test sees exactly the events sent by its Clients.
+func NewServer(t testing.TB, serv *ss.SocketServer) *Server {
+	t.Helper()
+
+	if serv == nil {
+		serv = ss.NewServer()
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/socket", serv)
+
+	srv := &Server{
+		SocketServer: serv,
+		HTTP:         httptest.NewServer(mux),
+		t:            t,
+		closeOnce:    &sync.Once{},
+	}
+	srv.URL = "ws" + strings.TrimPrefix(srv.HTTP.URL, "http") + "/socket"
+
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+//Close shuts down the SocketServer, closing all of its Sockets, and then closes the httptest.Server.
+//Close may be called more than once.
+func (srv *Server) Close() {
+	srv.closeOnce.Do(func() {
+		srv.SocketServer.Shutdown()
+		srv.HTTP.Close()
+	})
+}
+
+//Dial connects a new Client to the Server, failing the test if the connection can not be made.
+//Dial does not return until the Client's Socket has been registered with the SocketServer.
+func (srv *Server) Dial() *Client {
+	srv.t.Helper()
+	return srv.DialHeader(nil)
+}
+
+//DialHeader is the same as Dial, but sends header with the websocket handshake
+func (srv *Server) DialHeader(header http.Header) *Client {
+	srv.t.Helper()
+
+	c, err := Dial(srv.URL, header)
+	if err != nil {
+		srv.t.Fatalf("sstest: dial %s: %v", srv.URL, err)
+	}
+	c.t = srv.t
+	srv.t.Cleanup(c.Close)
+
+	//the Client's local address is the remote address of its Socket
+	addr := c.ws.LocalAddr().String()
+	deadline := time.Now().Add(c.Timeout)
+	for c.id == "" {
+		for _, s := range srv.GetSockets() {
+			if s.RemoteAddr().String() == addr {
+				c.id = s.ID()
+			}
+		}
+
+		if c.id == "" {
+			if time.Now().After(deadline) {
+				srv.t.Fatalf("sstest: the Socket of %s was not registered", addr)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return c
+}
+
+//Client is a sac-sock websocket client for use in tests.
+//
+//Methods that wait for something to happen fail the test after Timeout has elapsed, so like testing.T.FailNow
+//they must only be called from the goroutine running the test.
+type Client struct {
+	//Timeout is how long Expect and ExpectClosed will wait, it defaults to DefaultTimeout
+	Timeout time.Duration
+
+	t      testing.TB
+	id     string
+	ws     *websocket.Conn
+	wl     *sync.Mutex
+	l      *sync.Mutex
+	queue  []*ss.Message
+	notify chan struct{}
+	done   chan struct{}
+	err    error
+}
+
+//Dial connects to the sac-sock websocket at url. Clients returned by Dial are not bound to a test, so
+//Expect and ExpectClosed will panic instead of failing a test. Most tests should use Server.Dial instead.
+func Dial(url string, header http.Header) (*Client, error) {
+	d := &websocket.Dialer{
+		Subprotocols:     []string{ss.SubProtocol},
+		HandshakeTimeout: DefaultTimeout,
+	}
+
+	ws, _, err := d.Dial(url, header)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		Timeout: DefaultTimeout,
+		ws:      ws,
+		wl:      &sync.Mutex{},
+		l:       &sync.Mutex{},
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	go c.read()
+	return c, nil
+}
+
+func (c *Client) read() {
+	defer close(c.done)
+
+	for {
+		msgType, frame, err := c.ws.ReadMessage()
+		if err != nil {
+			c.l.Lock()
+			c.err = err
+			c.l.Unlock()
+			return
+		}
+
+		eventName, dataType, payload, err := ssproto.Decode(frame)
+		if err != nil {
+			continue
+		}
+		if dataType == ssproto.TypeUnknown {
+			dataType = ssproto.TypeStr
+			if msgType == websocket.BinaryMessage {
+				dataType = ssproto.TypeBin
+			}
+		}
+
+		c.l.Lock()
+		c.queue = append(c.queue, &ss.Message{EventName: eventName, Type: dataType, Raw: payload})
+		c.l.Unlock()
+
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+//ID returns the ID of the client's Socket on the server. It is only known for Clients created with Server.Dial.
+func (c *Client) ID() string {
+	return c.id
+}
+
+//Emit sends an event to the server. data is encoded the same way Socket.Emit encodes it.
+func (c *Client) Emit(eventName string, data interface{}) error {
+	frame, dataType, err := ssproto.Encode(eventName, data)
+	if err != nil {
+		return err
+	}
+
+	msgType := websocket.TextMessage
+	if dataType == ssproto.TypeBin {
+		msgType = websocket.BinaryMessage
+	}
+
+	c.wl.Lock()
+	defer c.wl.Unlock()
+	return c.ws.WriteMessage(msgType, frame)
+}
+
+//Next waits for the next event of any name and returns it. If the connection is closed or timeout elapses
+//first, an error is returned.
+func (c *Client) Next(timeout time.Duration) (*ss.Message, error) {
+	return c.wait("", timeout)
+}
+
+//Receive waits for an event named eventName. Events with other names received in the meantime are kept
+//for later calls to Next, Receive, or Expect. If the connection is closed or timeout elapses first, an
+//error is returned.
+func (c *Client) Receive(eventName string, timeout time.Duration) (*ss.Message, error) {
+	return c.wait(eventName, timeout)
+}
+
+//Expect is the same as Receive, but fails the test if eventName is not received within Timeout
+func (c *Client) Expect(eventName string) *ss.Message {
+	msg, err := c.wait(eventName, c.Timeout)
+	if err != nil {
+		c.fatalf("sstest: expected event %q: %v", eventName, err)
+	}
+	return msg
+}
+
+//ExpectNone fails the test if an event named eventName is received within wait
+func (c *Client) ExpectNone(eventName string, wait time.Duration) {
+	msg, err := c.wait(eventName, wait)
+	if err == nil {
+		c.fatalf("sstest: unexpected event %q: %s", eventName, msg)
+	}
+}
+
+//ExpectClosed fails the test if the server does not close the connection within Timeout
+func (c *Client) ExpectClosed() {
+	select {
+	case <-c.done:
+	case <-time.After(c.Timeout):
+		c.fatalf("sstest: expected the connection to be closed by the server")
+	}
+}
+
+//Closed returns true if the connection has been closed by either side
+func (c *Client) Closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+//Err returns the error that closed the connection, or nil if it is still open. If the server closed the connection
+//with a close frame, Err returns a *websocket.CloseError.
+func (c *Client) Err() error {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return c.err
+}
+
+//Close sends a normal closure close frame and closes the connection
+func (c *Client) Close() {
+	c.CloseWithReason(websocket.CloseNormalClosure, "")
+}
+
+//CloseWithReason sends a close frame with code and text and closes the connection
+func (c *Client) CloseWithReason(code int, text string) {
+	c.wl.Lock()
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	c.wl.Unlock()
+	c.ws.Close()
+}
+
+//Disconnect closes the underlying network connection without sending a close frame, simulating
+//a client that has lost its connection
+func (c *Client) Disconnect() {
+	c.ws.UnderlyingConn().Close()
+}
+
+//wait waits for the first message named eventName, or any message if eventName is empty
+func (c *Client) wait(eventName string, timeout time.Duration) (*ss.Message, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		if msg := c.pop(eventName); msg != nil {
+			return msg, nil
+		}
+
+		select {
+		case <-c.notify:
+		case <-c.done:
+			//the connection may have been closed right after the message arrived
+			if msg := c.pop(eventName); msg != nil {
+				return msg, nil
+			}
+			return nil, ErrClientClosed
+		case <-deadline.C:
+			return nil, ErrTimeout
+		}
+	}
+}
+
+//pop removes and returns the first queued message named eventName, or any message if eventName is empty
+func (c *Client) pop(eventName string) *ss.Message {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	for i, msg := range c.queue {
+		if eventName == "" || msg.EventName == eventName {
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			return msg
+		}
+	}
+	return nil
+}
+
+func (c *Client) fatalf(format string, args ...interface{}) {
+	if c.t == nil {
+		panic(fmt.Sprintf(format, args...))
+	}
+	c.t.Helper()
+	c.t.Fatalf(format, args...)
+}
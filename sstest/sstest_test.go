@@ -0,0 +1,127 @@
+package sstest
+
+import (
+	ss "github.com/raz-varren/sacrificial-socket"
+	"testing"
+	"time"
+)
+
+func newEchoServer() *ss.SocketServer {
+	serv := ss.NewServer()
+	serv.OnMessage("echo", func(s *ss.Socket, msg *ss.Message) {
+		if msg.IsString() {
+			s.Emit("echo", msg.String())
+			return
+		}
+		s.Emit("echo", msg.Raw)
+	})
+	serv.On("join", func(s *ss.Socket, data []byte) {
+		s.Join(string(data))
+		s.Emit("joined", string(data))
+	})
+	return serv
+}
+
+func TestEcho(t *testing.T) {
+	c := NewServer(t, newEchoServer()).Dial()
+
+	c.Emit("echo", "hello")
+	if msg := c.Expect("echo"); msg.String() != "hello" {
+		t.Errorf("expected hello, got %s", msg)
+	}
+
+	c.Emit("echo", []byte{0, 1, 2})
+	if msg := c.Expect("echo"); !msg.IsBinary() || msg.String() != "\x00\x01\x02" {
+		t.Errorf("expected binary echo, got %q", msg.Raw)
+	}
+
+	c.ExpectNone("echo", 50*time.Millisecond)
+}
+
+func TestDialSendsNoEvents(t *testing.T) {
+	serv := newEchoServer()
+	seen := make(chan string, 10)
+	serv.OnAny(func(s *ss.Socket, msg *ss.Message) {
+		seen <- msg.EventName
+	})
+	serv.OnUnknown(func(s *ss.Socket, msg *ss.Message) {
+		seen <- "unknown " + msg.EventName
+	})
+
+	srv := NewServer(t, serv)
+	c := srv.Dial()
+	if _, exists := srv.GetSocket(c.ID()); !exists {
+		t.Fatalf("expected socket %s to be connected", c.ID())
+	}
+
+	c.Emit("echo", "hello")
+	c.Expect("echo")
+
+	select {
+	case ev := <-seen:
+		if ev != "echo" {
+			t.Errorf("expected echo to be the first event, got %s", ev)
+		}
+	case <-time.After(DefaultTimeout):
+		t.Fatal("OnAny was not called")
+	}
+	if len(seen) != 0 {
+		t.Errorf("expected no other events, got %d more", len(seen))
+	}
+}
+
+func TestRoomcast(t *testing.T) {
+	srv := NewServer(t, newEchoServer())
+	a, b, outsider := srv.Dial(), srv.Dial(), srv.Dial()
+
+	a.Emit("join", "lobby")
+	a.Expect("joined")
+	b.Emit("join", "lobby")
+	b.Expect("joined")
+
+	srv.Roomcast("lobby", "news", "hi")
+	a.Expect("news")
+	b.Expect("news")
+	outsider.ExpectNone("news", 50*time.Millisecond)
+}
+
+func TestDisconnect(t *testing.T) {
+	serv := newEchoServer()
+	disconnected := make(chan string, 1)
+	serv.OnDisconnect(func(s *ss.Socket) {
+		disconnected <- s.ID()
+	})
+
+	c := NewServer(t, serv).Dial()
+	c.Disconnect()
+
+	select {
+	case id := <-disconnected:
+		if id != c.ID() {
+			t.Errorf("expected socket %s to disconnect, got %s", c.ID(), id)
+		}
+	case <-time.After(DefaultTimeout):
+		t.Fatal("OnDisconnect was not called")
+	}
+}
+
+func TestCluster(t *testing.T) {
+	cl := NewCluster(t, 3, newEchoServer)
+	a, b, c := cl.Node(0).Dial(), cl.Node(1).Dial(), cl.Node(2).Dial()
+
+	cl.Node(0).Broadcast("news", "everyone")
+	for _, client := range []*Client{a, b, c} {
+		client.Expect("news")
+	}
+
+	b.Emit("join", "lobby")
+	b.Expect("joined")
+	cl.Node(2).Roomcast("lobby", "news", "lobby only")
+	if msg := b.Expect("news"); msg.String() != "lobby only" {
+		t.Errorf("expected lobby only, got %s", msg)
+	}
+	a.ExpectNone("news", 50*time.Millisecond)
+
+	cl.Node(0).Kick(c.ID())
+	c.ExpectClosed()
+}
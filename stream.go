@@ -0,0 +1,449 @@
+package ss
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/raz-varren/log"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//StreamChunkSize is the maximum number of payload bytes sent in a single stream chunk
+	StreamChunkSize int = 32 * 1024
+
+	//StreamWindow is the maximum number of chunks a stream sender may have in flight
+	//before it must wait for the receiver to acknowledge them
+	StreamWindow int = 16
+
+	//StreamAckTimeout is how long Socket.EmitStream will wait for the client to
+	//acknowledge a chunk before giving up on the stream
+	StreamAckTimeout = 30 * time.Second
+
+	//DefaultMaxStreams is the default maximum number of streams a single client may be uploading
+	//at once, see SocketServer.SetMaxStreams
+	DefaultMaxStreams = 8
+
+	streamPrefix      = "__ss_stream"
+	streamOpenEvent   = "__ss_stream_open"
+	streamChunkPrefix = "__ss_stream_chunk:"
+	streamEndEvent    = "__ss_stream_end"
+	streamAckEvent    = "__ss_stream_ack"
+	streamAbortEvent  = "__ss_stream_abort"
+)
+
+var (
+	ErrSocketClosed     = errors.New("socket is closed")
+	ErrStreamTimeout    = errors.New("timed out waiting for stream acknowledgement")
+	ErrStreamOverflow   = errors.New("stream sender exceeded the stream window")
+	ErrNoStreamHandler  = errors.New("no stream handler registered for event")
+	ErrDuplicateStream  = errors.New("stream id is already in use")
+	ErrTooManyStreams   = errors.New("too many concurrent streams")
+	ErrStreamReaderDone = errors.New("stream handler stopped reading")
+)
+
+//streamMsg is the JSON payload of every stream control event
+type streamMsg struct {
+	ID    string `json:"id"`
+	Event string `json:"event,omitempty"`
+	Seq   uint64 `json:"seq,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+//inStream is a stream being uploaded by the client. chunks is only ever sent to by the Socket's
+//read loop and is never closed, done is closed by endIn once the stream has ended.
+type inStream struct {
+	id     string
+	chunks chan []byte
+	done   chan struct{}
+	err    error
+	pr     *io.PipeReader
+	pw     *io.PipeWriter
+}
+
+//outStream is a stream being sent to the client by Socket.EmitStream. Aborts have their own
+//channel so they are never lost behind acks, which are cumulative and may be dropped.
+type outStream struct {
+	acks  chan uint64
+	abort chan error
+}
+
+type streamSet struct {
+	l   *sync.Mutex
+	in  map[string]*inStream
+	out map[string]*outStream
+}
+
+func newStreamSet() *streamSet {
+	return &streamSet{
+		l:   &sync.Mutex{},
+		in:  make(map[string]*inStream),
+		out: make(map[string]*outStream),
+	}
+}
+
+func newStreamID() string {
+	idBuf := make([]byte, 8)
+	socketRNG.Read(idBuf)
+	return hex.EncodeToString(idBuf)
+}
+
+//addIn adds the inbound stream unless its id is in use or there are already max inbound streams
+func (ss *streamSet) addIn(in *inStream, max int) error {
+	ss.l.Lock()
+	defer ss.l.Unlock()
+	if _, exists := ss.in[in.id]; exists {
+		return ErrDuplicateStream
+	}
+	if max > 0 && len(ss.in) >= max {
+		return ErrTooManyStreams
+	}
+	ss.in[in.id] = in
+	return nil
+}
+
+func (ss *streamSet) getIn(id string) *inStream {
+	ss.l.Lock()
+	defer ss.l.Unlock()
+	return ss.in[id]
+}
+
+//endIn removes the inbound stream and closes its done channel, err will be returned
+//to the stream handler once it has read all of the chunks already received
+func (ss *streamSet) endIn(id string, err error) bool {
+	ss.l.Lock()
+	in, exists := ss.in[id]
+	delete(ss.in, id)
+	ss.l.Unlock()
+
+	if !exists {
+		return false
+	}
+
+	in.err = err
+	close(in.done)
+	return true
+}
+
+func (ss *streamSet) addOut(id string, out *outStream) {
+	ss.l.Lock()
+	defer ss.l.Unlock()
+	ss.out[id] = out
+}
+
+func (ss *streamSet) getOut(id string) *outStream {
+	ss.l.Lock()
+	defer ss.l.Unlock()
+	return ss.out[id]
+}
+
+func (ss *streamSet) delOut(id string) {
+	ss.l.Lock()
+	defer ss.l.Unlock()
+	delete(ss.out, id)
+}
+
+//closeAll ends every inbound stream with err
+func (ss *streamSet) closeAll(err error) {
+	ss.l.Lock()
+	var ids []string
+	for id := range ss.in {
+		ids = append(ids, id)
+	}
+	ss.l.Unlock()
+
+	for _, id := range ids {
+		ss.endIn(id, err)
+	}
+}
+
+//pump writes received chunks into the pipe read by the stream handler, acknowledging
+//each chunk once the handler has consumed it
+func (in *inStream) pump(s *Socket) {
+	var seq uint64
+
+	for {
+		var chunk []byte
+
+		select {
+		case chunk = <-in.chunks:
+		case <-in.done:
+			//the read loop sends every chunk before ending the stream, so whatever is left is buffered
+			select {
+			case chunk = <-in.chunks:
+			default:
+				in.pw.CloseWithError(in.err)
+				return
+			}
+		}
+
+		_, err := in.pw.Write(chunk)
+		if err != nil {
+			s.streams.endIn(in.id, err)
+			s.Emit(streamAbortEvent, &streamMsg{ID: in.id, Error: err.Error()})
+			return
+		}
+
+		seq++
+		s.Emit(streamAckEvent, &streamMsg{ID: in.id, Seq: seq})
+	}
+}
+
+//EmitStream reads r until EOF and sends its contents to s as a sequence of chunks of at most
+//StreamChunkSize bytes. Each chunk is sent as soon as a single Read of r returns it, so slow readers
+//such as pipes are streamed as they produce data. No more than StreamWindow chunks will be in flight
+//at any one time.
+//
+//The client receives the stream with a handler registered using the JS client's onStream method.
+//
+//EmitStream blocks until r has been fully sent, r returns an error, the client aborts
+//the stream, or s is closed.
+func (s *Socket) EmitStream(eventName string, r io.Reader) error {
+	id := newStreamID()
+	out := &outStream{
+		acks:  make(chan uint64, StreamWindow*2),
+		abort: make(chan error, 1),
+	}
+
+	s.streams.addOut(id, out)
+	defer s.streams.delOut(id)
+
+	err := s.Emit(streamOpenEvent, &streamMsg{ID: id, Event: eventName})
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, StreamChunkSize)
+	var sent, acked uint64
+
+	for {
+		n, rErr := r.Read(buf)
+
+		if n > 0 {
+			//always check for acks and aborts, but only block when the window is full
+			for block := false; ; block = true {
+				acked, err = s.streamAcks(out, acked, block)
+				if err != nil {
+					return err
+				}
+				if acked > sent { //misbehaving client, don't let it open the window any further
+					acked = sent
+				}
+				if sent-acked < uint64(StreamWindow) {
+					break
+				}
+			}
+
+			d, _, err := emitData(streamChunkPrefix+id, buf[:n])
+			if err != nil {
+				return err
+			}
+
+			err = s.send(websocket.BinaryMessage, d)
+			if err != nil {
+				return err
+			}
+			sent++
+		}
+
+		if rErr == io.EOF {
+			break
+		}
+
+		if rErr != nil {
+			s.Emit(streamEndEvent, &streamMsg{ID: id, Error: rErr.Error()})
+			return rErr
+		}
+	}
+
+	return s.Emit(streamEndEvent, &streamMsg{ID: id})
+}
+
+//streamAcks consumes acknowledgements for out and returns the highest acknowledged sequence
+//number, or the client's error if it aborted the stream. If block is true streamAcks waits for
+//at least one acknowledgement.
+func (s *Socket) streamAcks(out *outStream, acked uint64, block bool) (uint64, error) {
+	var timeout <-chan time.Time
+	if block {
+		timeout = time.After(StreamAckTimeout)
+	}
+
+	for {
+		var seq uint64
+
+		//an abort wins over any acks received before it
+		select {
+		case err := <-out.abort:
+			return acked, err
+		default:
+		}
+
+		if block {
+			select {
+			case seq = <-out.acks:
+				block = false
+			case err := <-out.abort:
+				return acked, err
+			case <-s.done:
+				return acked, ErrSocketClosed
+			case <-timeout:
+				return acked, ErrStreamTimeout
+			}
+		} else {
+			select {
+			case seq = <-out.acks:
+			default:
+				return acked, nil
+			}
+		}
+
+		if seq > acked {
+			acked = seq
+		}
+	}
+}
+
+//OnStream registers a function to be called whenever the client begins streaming
+//data to eventName using the JS client's emitStream method. The stream's contents are
+//read from r, which returns io.EOF once the client has finished sending.
+//
+//The client will not send more than StreamWindow chunks until handleFunc has
+//read the chunks already received. If handleFunc returns before reading all of r,
+//the rest of the stream is aborted.
+func (serv *SocketServer) OnStream(eventName string, handleFunc func(s *Socket, r io.Reader)) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.streamEvents[eventName] = handleFunc
+}
+
+//SetMaxStreams sets the maximum number of streams a single client may be uploading to OnStream handlers at
+//once. Streams opened beyond the maximum are aborted with ErrTooManyStreams. The maximum defaults to
+//DefaultMaxStreams, a maximum of 0 or less removes it.
+func (serv *SocketServer) SetMaxStreams(max int) {
+	serv.l.Lock()
+	defer serv.l.Unlock()
+	serv.maxStreams = max
+}
+
+//limitStreamOpen applies the SocketServer's RateLimits to a stream open message as if it were an event
+//named after the stream's event. It returns false, and aborts the stream, if the stream must not be opened.
+func (serv *SocketServer) limitStreamOpen(s *Socket, rl *rateLimiter, msg *Message) bool {
+	var sm streamMsg
+	err := msg.Unmarshal(&sm)
+	if err != nil || sm.ID == "" {
+		return true //handleStreamMsg reports bad stream messages
+	}
+
+	if serv.limit(s, rl, &Message{EventName: sm.Event, Type: msg.Type, Raw: msg.Raw}) {
+		return true
+	}
+	s.Emit(streamAbortEvent, &streamMsg{ID: sm.ID, Error: "rate limited"})
+	return false
+}
+
+//handleStreamMsg processes the stream control events and chunks sent by the client.
+//It is called from the socket's read loop, so it must never block on the stream handler.
+func (serv *SocketServer) handleStreamMsg(s *Socket, msg *Message) {
+	if strings.HasPrefix(msg.EventName, streamChunkPrefix) {
+		id := msg.EventName[len(streamChunkPrefix):]
+		in := s.streams.getIn(id)
+		if in == nil {
+			return //stream was aborted, drop any chunks still in flight
+		}
+
+		select {
+		case in.chunks <- msg.Raw:
+		default:
+			s.streams.endIn(id, ErrStreamOverflow)
+			s.Emit(streamAbortEvent, &streamMsg{ID: id, Error: ErrStreamOverflow.Error()})
+		}
+		return
+	}
+
+	var sm streamMsg
+	err := msg.Unmarshal(&sm)
+	if err != nil || sm.ID == "" {
+		log.Warn.Println(s.ID(), "bad stream message:", msg.EventName)
+		return
+	}
+
+	switch msg.EventName {
+	case streamOpenEvent:
+		serv.l.RLock()
+		h, exists := serv.streamEvents[sm.Event]
+		maxStreams := serv.maxStreams
+		serv.l.RUnlock()
+
+		if !exists {
+			s.Emit(streamAbortEvent, &streamMsg{ID: sm.ID, Error: ErrNoStreamHandler.Error() + ": " + sm.Event})
+			return
+		}
+
+		if !serv.allowed(s, sm.Event, "") {
+			serv.deny(s, sm.Event)
+			s.Emit(streamAbortEvent, &streamMsg{ID: sm.ID, Error: "not allowed to emit " + sm.Event})
+			return
+		}
+
+		pr, pw := io.Pipe()
+		in := &inStream{
+			id:     sm.ID,
+			chunks: make(chan []byte, StreamWindow),
+			done:   make(chan struct{}),
+			pr:     pr,
+			pw:     pw,
+		}
+
+		err = s.streams.addIn(in, maxStreams)
+		if err != nil {
+			s.Emit(streamAbortEvent, &streamMsg{ID: sm.ID, Error: err.Error()})
+			return
+		}
+
+		go in.pump(s)
+		go func() {
+			defer pr.CloseWithError(ErrStreamReaderDone)
+			defer serv.recoverPanic(s, sm.Event)
+			h(s, pr)
+		}()
+
+	case streamEndEvent:
+		var endErr error
+		if sm.Error != "" {
+			endErr = errors.New(sm.Error)
+		}
+		s.streams.endIn(sm.ID, endErr)
+
+	case streamAckEvent:
+		out := s.streams.getOut(sm.ID)
+		if out == nil {
+			return
+		}
+
+		select {
+		case out.acks <- sm.Seq:
+		default: //acks are cumulative, dropping one is harmless
+		}
+
+	case streamAbortEvent:
+		out := s.streams.getOut(sm.ID)
+		if out == nil {
+			return
+		}
+
+		if sm.Error == "" {
+			sm.Error = "stream aborted by client"
+		}
+
+		select {
+		case out.abort <- errors.New(sm.Error):
+		default: //the stream is already being aborted
+		}
+
+	default:
+		log.Warn.Println(s.ID(), "unknown stream message:", msg.EventName)
+	}
+}
@@ -0,0 +1,346 @@
+package ss_test
+
+import (
+	"bytes"
+	"github.com/raz-varren/sacrificial-socket"
+	"github.com/raz-varren/sacrificial-socket/sstest"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type streamMsg struct {
+	ID    string `json:"id"`
+	Event string `json:"event,omitempty"`
+	Seq   uint64 `json:"seq,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func expectStreamMsg(t *testing.T, c *sstest.Client, eventName string) streamMsg {
+	t.Helper()
+	var sm streamMsg
+	if err := c.Expect(eventName).Unmarshal(&sm); err != nil {
+		t.Fatal(err)
+	}
+	return sm
+}
+
+//uploadHandler emits the contents of each upload, or the error that ended it, as a "done" event
+func uploadHandler(s *ss.Socket, r io.Reader) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		s.Emit("done", "error: "+err.Error())
+		return
+	}
+	s.Emit("done", string(data))
+}
+
+func TestStreamUpload(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnStream("upload", uploadHandler)
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up1", Event: "upload"})
+	for _, chunk := range []string{"one ", "two ", "three"} {
+		c.Emit("__ss_stream_chunk:up1", []byte(chunk))
+	}
+	c.Emit("__ss_stream_end", &streamMsg{ID: "up1"})
+
+	for seq := uint64(1); seq <= 3; seq++ {
+		if ack := expectStreamMsg(t, c, "__ss_stream_ack"); ack.ID != "up1" || ack.Seq != seq {
+			t.Errorf("expected ack %d of up1, got %+v", seq, ack)
+		}
+	}
+	if data := c.Expect("done").String(); data != "one two three" {
+		t.Errorf("expected the uploaded data, got %q", data)
+	}
+}
+
+func TestStreamUploadUnknownEvent(t *testing.T) {
+	c := sstest.NewServer(t, nil).Dial()
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up1", Event: "nope"})
+
+	if abort := expectStreamMsg(t, c, "__ss_stream_abort"); abort.ID != "up1" || abort.Error != ss.ErrNoStreamHandler.Error()+": nope" {
+		t.Errorf("expected a no handler abort, got %+v", abort)
+	}
+}
+
+func TestStreamUploadClientAbort(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnStream("upload", uploadHandler)
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up1", Event: "upload"})
+	c.Emit("__ss_stream_chunk:up1", []byte("partial"))
+	c.Emit("__ss_stream_end", &streamMsg{ID: "up1", Error: "cancelled"})
+
+	if data := c.Expect("done").String(); data != "error: cancelled" {
+		t.Errorf("expected the handler to read the client's error, got %q", data)
+	}
+
+	//chunks sent after the stream ended are dropped
+	expectStreamMsg(t, c, "__ss_stream_ack")
+	c.Emit("__ss_stream_chunk:up1", []byte("late"))
+	c.ExpectNone("__ss_stream_ack", 50*time.Millisecond)
+}
+
+func TestStreamUploadDuplicate(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnStream("upload", uploadHandler)
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up1", Event: "upload"})
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up1", Event: "upload"})
+
+	if abort := expectStreamMsg(t, c, "__ss_stream_abort"); abort.ID != "up1" || abort.Error != ss.ErrDuplicateStream.Error() {
+		t.Errorf("expected a duplicate stream abort, got %+v", abort)
+	}
+
+	//the first stream is unaffected
+	c.Emit("__ss_stream_chunk:up1", []byte("data"))
+	c.Emit("__ss_stream_end", &streamMsg{ID: "up1"})
+	if data := c.Expect("done").String(); data != "data" {
+		t.Errorf("expected the first stream to complete, got %q", data)
+	}
+}
+
+func TestStreamUploadMaxStreams(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnStream("upload", uploadHandler)
+	serv.SetMaxStreams(2)
+
+	c := sstest.NewServer(t, serv).Dial()
+	for _, id := range []string{"up1", "up2", "up3"} {
+		c.Emit("__ss_stream_open", &streamMsg{ID: id, Event: "upload"})
+	}
+
+	if abort := expectStreamMsg(t, c, "__ss_stream_abort"); abort.ID != "up3" || abort.Error != ss.ErrTooManyStreams.Error() {
+		t.Errorf("expected a too many streams abort for up3, got %+v", abort)
+	}
+
+	//ending a stream makes room for another
+	c.Emit("__ss_stream_end", &streamMsg{ID: "up1"})
+	c.Expect("done")
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up4", Event: "upload"})
+	c.ExpectNone("__ss_stream_abort", 50*time.Millisecond)
+}
+
+func TestStreamUploadOverflow(t *testing.T) {
+	release := make(chan struct{})
+
+	serv := ss.NewServer()
+	serv.OnStream("upload", func(s *ss.Socket, r io.Reader) {
+		<-release
+		uploadHandler(s, r)
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("__ss_stream_open", &streamMsg{ID: "up1", Event: "upload"})
+
+	//one chunk blocks in the pipe and StreamWindow chunks fill the buffer, the rest overflow it
+	for i := 0; i < ss.StreamWindow+2; i++ {
+		c.Emit("__ss_stream_chunk:up1", []byte("x"))
+	}
+
+	if abort := expectStreamMsg(t, c, "__ss_stream_abort"); abort.ID != "up1" || abort.Error != ss.ErrStreamOverflow.Error() {
+		t.Errorf("expected an overflow abort, got %+v", abort)
+	}
+
+	close(release)
+	if data := c.Expect("done").String(); data != "error: "+ss.ErrStreamOverflow.Error() {
+		t.Errorf("expected the handler to read the overflow error, got %q", data)
+	}
+}
+
+func TestStreamUploadHandlerReturnsEarly(t *testing.T) {
+	serv := ss.NewServer()
+	serv.OnStream("upload", func(s *ss.Socket, r io.Reader) {
+		buf := make([]byte, 1)
+		r.Read(buf)
+	})
+	serv.SetMaxStreams(0)
+	serv.On("ping", func(s *ss.Socket, data []byte) {
+		s.Emit("pong", "")
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+
+	//keep sending chunks to many streams while their handlers return, the read loop must survive
+	const streams = 100
+	for i := 0; i < streams; i++ {
+		c.Emit("__ss_stream_open", &streamMsg{ID: "up" + strconv.Itoa(i), Event: "upload"})
+	}
+	for n := 0; n < ss.StreamWindow; n++ {
+		for i := 0; i < streams; i++ {
+			c.Emit("__ss_stream_chunk:up"+strconv.Itoa(i), []byte("xy"))
+		}
+	}
+
+	if abort := expectStreamMsg(t, c, "__ss_stream_abort"); abort.Error != ss.ErrStreamReaderDone.Error() {
+		t.Errorf("expected a reader done abort, got %+v", abort)
+	}
+
+	c.Emit("ping", "")
+	c.Expect("pong")
+}
+
+func TestEmitStream(t *testing.T) {
+	chunks := ss.StreamWindow + 4
+	data := bytes.Repeat([]byte("z"), chunks*ss.StreamChunkSize-10)
+	sent := make(chan error, 1)
+
+	serv := ss.NewServer()
+	serv.On("download", func(s *ss.Socket, _ []byte) {
+		sent <- s.EmitStream("file", bytes.NewReader(data))
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("download", "")
+
+	open := expectStreamMsg(t, c, "__ss_stream_open")
+	if open.Event != "file" {
+		t.Fatalf("expected the file event, got %+v", open)
+	}
+	chunkEvent := "__ss_stream_chunk:" + open.ID
+
+	//without acks the server stops once the window is full
+	var received []byte
+	for i := 0; i < ss.StreamWindow; i++ {
+		received = append(received, c.Expect(chunkEvent).Raw...)
+	}
+	c.ExpectNone(chunkEvent, 50*time.Millisecond)
+
+	c.Emit("__ss_stream_ack", &streamMsg{ID: open.ID, Seq: uint64(ss.StreamWindow)})
+	for i := ss.StreamWindow; i < chunks; i++ {
+		received = append(received, c.Expect(chunkEvent).Raw...)
+	}
+	c.Emit("__ss_stream_ack", &streamMsg{ID: open.ID, Seq: uint64(chunks)})
+
+	if end := expectStreamMsg(t, c, "__ss_stream_end"); end.ID != open.ID || end.Error != "" {
+		t.Errorf("expected a clean end, got %+v", end)
+	}
+	if !bytes.Equal(received, data) {
+		t.Errorf("expected %d bytes, received %d", len(data), len(received))
+	}
+
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Errorf("expected EmitStream to succeed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("EmitStream did not return")
+	}
+}
+
+func TestEmitStreamClientAbort(t *testing.T) {
+	sent := make(chan error, 1)
+
+	serv := ss.NewServer()
+	serv.On("download", func(s *ss.Socket, _ []byte) {
+		sent <- s.EmitStream("file", bytes.NewReader(make([]byte, ss.StreamWindow*4*ss.StreamChunkSize)))
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("download", "")
+
+	open := expectStreamMsg(t, c, "__ss_stream_open")
+	c.Emit("__ss_stream_abort", &streamMsg{ID: open.ID, Error: "no thanks"})
+
+	select {
+	case err := <-sent:
+		if err == nil || err.Error() != "no thanks" {
+			t.Errorf("expected the client's abort error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("EmitStream did not return")
+	}
+
+	//at most a window's worth of chunks was sent
+	n := 0
+	for {
+		if _, err := c.Receive("__ss_stream_chunk:"+open.ID, 20*time.Millisecond); err != nil {
+			break
+		}
+		n++
+	}
+	if n > ss.StreamWindow {
+		t.Errorf("expected at most %d chunks, got %d", ss.StreamWindow, n)
+	}
+}
+
+func TestEmitStreamAbortAfterAcks(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	sent := make(chan error, 1)
+
+	serv := ss.NewServer()
+	serv.On("download", func(s *ss.Socket, _ []byte) {
+		sent <- s.EmitStream("file", pr)
+	})
+	serv.On("sync", func(s *ss.Socket, _ []byte) {
+		s.Emit("synced", "")
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("download", "")
+	open := expectStreamMsg(t, c, "__ss_stream_open")
+
+	//flood EmitStream with acks while it is blocked reading, the abort must not be lost behind them
+	for i := 0; i < ss.StreamWindow*4; i++ {
+		c.Emit("__ss_stream_ack", &streamMsg{ID: open.ID, Seq: 1})
+	}
+	c.Emit("__ss_stream_abort", &streamMsg{ID: open.ID, Error: "no thanks"})
+
+	//stream messages are handled in order by the read loop, so once synced the abort has been received
+	c.Emit("sync", "")
+	c.Expect("synced")
+	pw.Write([]byte("data"))
+
+	select {
+	case err := <-sent:
+		if err == nil || err.Error() != "no thanks" {
+			t.Errorf("expected the client's abort error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("EmitStream did not return")
+	}
+}
+
+//pieceReader returns one of its pieces from each call to Read
+type pieceReader struct {
+	pieces []string
+}
+
+func (r *pieceReader) Read(p []byte) (int, error) {
+	if len(r.pieces) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.pieces[0])
+	r.pieces = r.pieces[1:]
+	return n, nil
+}
+
+func TestEmitStreamChunkPerRead(t *testing.T) {
+	pieces := []string{"a", "bb", "ccc"}
+
+	serv := ss.NewServer()
+	serv.On("download", func(s *ss.Socket, _ []byte) {
+		s.EmitStream("file", &pieceReader{append([]string{}, pieces...)})
+	})
+
+	c := sstest.NewServer(t, serv).Dial()
+	c.Emit("download", "")
+	open := expectStreamMsg(t, c, "__ss_stream_open")
+
+	for _, piece := range pieces {
+		if chunk := c.Expect("__ss_stream_chunk:" + open.ID).String(); chunk != piece {
+			t.Errorf("expected a chunk of %q, got %q", piece, chunk)
+		}
+	}
+	if end := expectStreamMsg(t, c, "__ss_stream_end"); end.ID != open.ID || end.Error != "" {
+		t.Errorf("expected a clean end, got %+v", end)
+	}
+}